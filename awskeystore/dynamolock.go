@@ -0,0 +1,120 @@
+package awskeystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"strconv"
+	"time"
+)
+
+//DefaultLockTable is the DynamoDB table holding locks unless otherwise configured.
+const DefaultLockTable = "key-rotation-locks"
+
+//DynamoDBLocker provides leases shared between hosts using conditional writes to a DynamoDB table.  The table must have
+//a string partition key named "target".  Lapsed leases are taken over by the next holder; expiry is stored as epoch
+//seconds within "expires" so the table's TTL may be enabled on that attribute to remove abandoned locks.
+type DynamoDBLocker struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+	//Holder describes this process within the table, such as a host and user name.
+	Holder string
+	now    func() time.Time
+}
+
+//NewDynamoDBLocker creates a locker storing leases within the table.
+func NewDynamoDBLocker(client dynamodbiface.DynamoDBAPI, table string, holder string) *DynamoDBLocker {
+	return &DynamoDBLocker{client: client, table: table, Holder: holder, now: time.Now}
+}
+
+func epochSeconds(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+}
+
+func (d *DynamoDBLocker) Acquire(ctx context.Context, target string, duration time.Duration) (rotation.Lease, error) {
+	token, err := rotation.NewLockToken()
+	if err != nil {
+		return nil, err
+	}
+	now := d.now()
+	//Truncated to whole seconds so the lease never outlives the stored expiry.
+	expires := now.Add(duration).Truncate(time.Second)
+	_, err = d.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"target":  {S: aws.String(target)},
+			"holder":  {S: aws.String(d.Holder)},
+			"token":   {S: aws.String(token)},
+			"expires": epochSeconds(expires),
+		},
+		ConditionExpression:       aws.String("attribute_not_exists(#target) OR #expires <= :now"),
+		ExpressionAttributeNames:  map[string]*string{"#target": aws.String("target"), "#expires": aws.String("expires")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":now": epochSeconds(now)},
+	})
+	if isConditionFailed(err) {
+		return nil, d.held(ctx, target)
+	} else if err != nil {
+		return nil, fmt.Errorf("acquiring lock on %s: %w", target, err)
+	}
+	return &dynamoLease{locker: d, target: target, token: token, expires: expires}, nil
+}
+
+//held describes the current holder of the target's lock.
+func (d *DynamoDBLocker) held(ctx context.Context, target string) error {
+	heldErr := &rotation.LockHeldError{Target: target, Holder: "unknown"}
+	out, err := d.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            map[string]*dynamodb.AttributeValue{"target": {S: aws.String(target)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || out.Item == nil {
+		return heldErr
+	}
+	if holder := out.Item["holder"]; holder != nil {
+		heldErr.Holder = aws.StringValue(holder.S)
+	}
+	if expires := out.Item["expires"]; expires != nil {
+		if seconds, err := strconv.ParseInt(aws.StringValue(expires.N), 10, 64); err == nil {
+			heldErr.Expires = time.Unix(seconds, 0)
+		}
+	}
+	return heldErr
+}
+
+func isConditionFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+type dynamoLease struct {
+	locker  *DynamoDBLocker
+	target  string
+	token   string
+	expires time.Time
+}
+
+func (d *dynamoLease) Expires() time.Time {
+	return d.expires
+}
+
+//Release deletes the lock if it still belongs to this lease.
+func (d *dynamoLease) Release(ctx context.Context) error {
+	_, err := d.locker.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(d.locker.table),
+		Key:                       map[string]*dynamodb.AttributeValue{"target": {S: aws.String(d.target)}},
+		ConditionExpression:       aws.String("#token = :token"),
+		ExpressionAttributeNames:  map[string]*string{"#token": aws.String("token")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":token": {S: aws.String(d.token)}},
+	})
+	if isConditionFailed(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("releasing lock on %s: %w", d.target, err)
+	}
+	return nil
+}
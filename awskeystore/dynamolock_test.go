@@ -0,0 +1,124 @@
+package awskeystore
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeLockTable evaluates the conditional writes made by DynamoDBLocker against an in memory table.
+type fakeLockTable struct {
+	dynamodbiface.DynamoDBAPI
+	lock  sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeLockTable() *fakeLockTable {
+	return &fakeLockTable{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+func conditionFailed() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func (f *fakeLockTable) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, options ...request.Option) (*dynamodb.PutItemOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	target := aws.StringValue(in.Item["target"].S)
+	if existing, ok := f.items[target]; ok {
+		expires, _ := strconv.ParseInt(aws.StringValue(existing["expires"].N), 10, 64)
+		now, _ := strconv.ParseInt(aws.StringValue(in.ExpressionAttributeValues[":now"].N), 10, 64)
+		if expires > now {
+			return nil, conditionFailed()
+		}
+	}
+	f.items[target] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeLockTable) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, options ...request.Option) (*dynamodb.GetItemOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[aws.StringValue(in.Key["target"].S)]}, nil
+}
+
+func (f *fakeLockTable) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, options ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	target := aws.StringValue(in.Key["target"].S)
+	existing, ok := f.items[target]
+	if !ok || aws.StringValue(existing["token"].S) != aws.StringValue(in.ExpressionAttributeValues[":token"].S) {
+		return nil, conditionFailed()
+	}
+	delete(f.items, target)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func testDynamoDBLocker(table *fakeLockTable, holder string, at time.Time) *DynamoDBLocker {
+	locker := NewDynamoDBLocker(table, DefaultLockTable, holder)
+	locker.now = func() time.Time { return at }
+	return locker
+}
+
+func TestDynamoDBLockerRefusesHeldLock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, time.August, 1, 12, 0, 0, 0, time.UTC)
+	table := newFakeLockTable()
+	first := testDynamoDBLocker(table, "first", now)
+	second := testDynamoDBLocker(table, "second", now.Add(time.Minute))
+
+	lease, err := first.Acquire(ctx, "alice", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock because %s", err.Error())
+	}
+	if !lease.Expires().Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("Expected lease to expire after 5 minutes, got %s", lease.Expires())
+	}
+
+	_, err = second.Acquire(ctx, "alice", 5*time.Minute)
+	var held *rotation.LockHeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("Expected lock to be held, got %v", err)
+	}
+	if held.Holder != "first" || !held.Expires.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("Expected holder and expiry of first lease, got %+v", held)
+	}
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Failed to release lock because %s", err.Error())
+	}
+	if _, err := second.Acquire(ctx, "alice", 5*time.Minute); err != nil {
+		t.Errorf("Expected released lock to be acquired, got %v", err)
+	}
+}
+
+func TestDynamoDBLockerTakesOverExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, time.August, 1, 12, 0, 0, 0, time.UTC)
+	table := newFakeLockTable()
+	first := testDynamoDBLocker(table, "first", now)
+	second := testDynamoDBLocker(table, "second", now.Add(10*time.Minute))
+
+	stale, err := first.Acquire(ctx, "alice", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock because %s", err.Error())
+	}
+	if _, err := second.Acquire(ctx, "alice", 5*time.Minute); err != nil {
+		t.Fatalf("Expected expired lease to be taken over, got %v", err)
+	}
+	if err := stale.Release(ctx); err != nil {
+		t.Errorf("Expected releasing a lapsed lease to succeed, got %v", err)
+	}
+	if holder := aws.StringValue(table.items["alice"]["holder"].S); holder != "second" {
+		t.Errorf("Expected lock to remain held by second, got %q", holder)
+	}
+}
@@ -0,0 +1,44 @@
+package awskeystore
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"net/http"
+)
+
+//classify translates errors from the IAM API into a rotation.StoreError carrying the matching sentinel error.  The
+//original error remains available through errors.As.  Only listing and creating keys name the user, so only their
+//missing entities are the user itself; for other operations the missing entity is the key.
+func classify(operation rotation.OperationKind, username string, err error) error {
+	userScoped := operation == rotation.OperationList || operation == rotation.OperationCreate
+	return classifyCall(operation, username, userScoped, err)
+}
+
+//classifyCall classifies the error of an IAM call made on behalf of the operation.  A missing entity is classified as
+//rotation.ErrTargetNotFound only when the call is userScoped.
+func classifyCall(operation rotation.OperationKind, username string, userScoped bool, err error) error {
+	if err == nil {
+		return nil
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return err
+	}
+	storeErr := &rotation.StoreError{Operation: operation, Target: username, Err: err}
+	var failure awserr.RequestFailure
+	hasStatus := errors.As(err, &failure)
+	switch {
+	case userScoped && (awsErr.Code() == iam.ErrCodeNoSuchEntityException || (hasStatus && failure.StatusCode() == http.StatusNotFound)):
+		storeErr.Kind = rotation.ErrTargetNotFound
+	case awsErr.Code() == iam.ErrCodeLimitExceededException:
+		storeErr.Kind = rotation.ErrNoCapacity
+	case request.IsErrorThrottle(awsErr) || (hasStatus && failure.StatusCode() == http.StatusTooManyRequests):
+		storeErr.Kind = rotation.ErrThrottled
+	case awsErr.Code() == "AccessDenied" || (hasStatus && failure.StatusCode() == http.StatusForbidden):
+		storeErr.Kind = rotation.ErrPermissionDenied
+	}
+	return storeErr
+}
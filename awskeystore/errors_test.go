@@ -0,0 +1,58 @@
+package awskeystore
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"no such entity", awserr.NewRequestFailure(awserr.New("NoSuchEntity", "missing", nil), 404, "req"), rotation.ErrTargetNotFound},
+		{"limit exceeded", awserr.NewRequestFailure(awserr.New("LimitExceeded", "too many keys", nil), 409, "req"), rotation.ErrNoCapacity},
+		{"throttling", awserr.NewRequestFailure(awserr.New("Throttling", "Rate exceeded", nil), 400, "req"), rotation.ErrThrottled},
+		{"access denied", awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil), 403, "req"), rotation.ErrPermissionDenied},
+		{"server error", awserr.NewRequestFailure(awserr.New("ServiceFailure", "internal", nil), 500, "req"), nil},
+	}
+	for _, c := range cases {
+		err := classify(rotation.OperationCreate, "alice", c.err)
+		var storeErr *rotation.StoreError
+		if !errors.As(err, &storeErr) {
+			t.Errorf("Expected %s to produce a StoreError, got %v", c.name, err)
+			continue
+		}
+		if storeErr.Kind != c.kind || storeErr.Target != "alice" || storeErr.Operation != rotation.OperationCreate {
+			t.Errorf("Expected %s to be classified as %v, got %+v", c.name, c.kind, storeErr)
+		}
+		if c.kind != nil && !errors.Is(err, c.kind) {
+			t.Errorf("Expected %s to match %v", c.name, c.kind)
+		}
+		var awsErr awserr.RequestFailure
+		if !errors.As(err, &awsErr) || IsRetryable(err) != IsRetryable(c.err) {
+			t.Errorf("Expected %s to retain the AWS error, got %v", c.name, err)
+		}
+	}
+
+	missing := awserr.NewRequestFailure(awserr.New("NoSuchEntity", "missing", nil), 404, "req")
+	for _, operation := range []rotation.OperationKind{rotation.OperationDelete, rotation.OperationDisable, rotation.OperationEnable} {
+		if err := classify(operation, "alice", missing); errors.Is(err, rotation.ErrTargetNotFound) {
+			t.Errorf("Expected a missing key during %s not to be classified as a missing target", operation)
+		}
+	}
+	if err := classifyCall(rotation.OperationList, "alice", false, missing); errors.Is(err, rotation.ErrTargetNotFound) {
+		t.Error("Expected a missing key during a key scoped lookup not to be classified as a missing target")
+	}
+	if err := classify(rotation.OperationList, "alice", missing); !errors.Is(err, rotation.ErrTargetNotFound) {
+		t.Errorf("Expected a missing user while listing to be classified as a missing target, got %v", err)
+	}
+
+	plain := errors.New("connection reset")
+	if err := classify(rotation.OperationDelete, "alice", plain); err != plain {
+		t.Errorf("Expected errors not from AWS to be returned unchanged, got %v", err)
+	}
+}
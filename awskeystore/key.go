@@ -1,6 +1,8 @@
 package awskeystore
 
 import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/truewhitespace/key-rotation/rotation"
 	"time"
@@ -13,14 +15,63 @@ type AWSAccessKey struct {
 	//Secret is the secret key for the given ID.  This is only valid when the key has been created an will be null at
 	//all other times.
 	Secret *string
+	//UserName is the IAM user owning the key.
+	UserName string
+	//LastUsedDate is when AWS last recorded the key being used.  nil when the key has never been used or usage has not
+	//been queried.
+	LastUsedDate *time.Time
+	//LastUsedService is the AWS service the key was last used against.
+	LastUsedService string
+	//LastUsedRegion is the AWS region the key was last used within.
+	LastUsedRegion string
 	//Internalized time the AWS API reports the key has been created.
 	created time.Time
+	//inactive is true when AWS reports the key has been deactivated.
+	inactive bool
 }
 
 func (a *AWSAccessKey) Created() time.Time {
 	return a.created
 }
 
+//Active is false when the key has been deactivated within IAM.
+func (a *AWSAccessKey) Active() bool {
+	return !a.inactive
+}
+
+//LastUsed reports when the key was last used according to AWS.
+func (a *AWSAccessKey) LastUsed() (time.Time, bool) {
+	if a.LastUsedDate == nil {
+		return time.Time{}, false
+	}
+	return *a.LastUsedDate, true
+}
+
+//KeyID is the AWS access key ID.
+func (a *AWSAccessKey) KeyID() string {
+	return a.ID
+}
+
+//SecretMaterial is the secret access key, only known when the key has just been created.
+func (a *AWSAccessKey) SecretMaterial() (string, bool) {
+	if a.Secret == nil {
+		return "", false
+	}
+	return *a.Secret, true
+}
+
+//Description names the IAM user owning the key and where AWS last recorded its use.
+func (a *AWSAccessKey) Description() string {
+	description := "access key"
+	if a.UserName != "" {
+		description += " of " + a.UserName
+	}
+	if a.LastUsedService != "" {
+		description += fmt.Sprintf(", last used via %s in %s", a.LastUsedService, a.LastUsedRegion)
+	}
+	return description
+}
+
 //MaybeSecret converts teh possible secret value into a humanized form.
 func (a *AWSAccessKey) MaybeSecret() string {
 	if a.Secret == nil {
@@ -30,37 +81,51 @@ func (a *AWSAccessKey) MaybeSecret() string {
 	}
 }
 
-//internalizeKeyFromKey takes an AWS IAM key to create an AWSAccessKey.  Key which are not `Active` will be noted with
-//an invalid creation time.
+//internalizeKeyFromKey takes an AWS IAM key to create an AWSAccessKey.  Keys without a creation time will be noted
+//with an invalid creation time.
 func internalizeKeyFromKey(k *iam.AccessKey) *AWSAccessKey {
-	status := *k.Status
-	var createdAt time.Time
-	if status == "Active" {
-		createdAt = *k.CreateDate
-	} else {
-		createdAt = rotation.InvalidTime()
-	}
-
 	return &AWSAccessKey{
-		ID:      *k.AccessKeyId,
-		Secret:  k.SecretAccessKey,
-		created: createdAt,
+		ID:       *k.AccessKeyId,
+		Secret:   k.SecretAccessKey,
+		UserName: aws.StringValue(k.UserName),
+		created:  internalizeCreated(k.CreateDate),
+		inactive: internalizeInactive(k.Status),
 	}
 }
 
 //internalizeKeyFromMetadata takes an AWS IAM key to create an AWSAccessKey
 func internalizeKeyFromMetadata(k *iam.AccessKeyMetadata) *AWSAccessKey {
-	status := *k.Status
-	var createdAt time.Time
-	if status == "Active" {
-		createdAt = *k.CreateDate
-	} else {
-		createdAt = rotation.InvalidTime()
+	return &AWSAccessKey{
+		ID:       *k.AccessKeyId,
+		Secret:   nil,
+		UserName: aws.StringValue(k.UserName),
+		created:  internalizeCreated(k.CreateDate),
+		inactive: internalizeInactive(k.Status),
 	}
+}
 
-	return &AWSAccessKey{
-		ID:      *k.AccessKeyId,
-		Secret:  nil,
-		created: createdAt,
+func internalizeCreated(createDate *time.Time) time.Time {
+	if createDate == nil {
+		return rotation.InvalidTime()
+	}
+	return *createDate
+}
+
+func internalizeInactive(status *string) bool {
+	return status == nil || *status != iam.StatusTypeActive
+}
+
+//internalizeLastUsed records the usage AWS reports for the key.  AWS reports `N/A` for the service and region of keys
+//which have never been used.
+func internalizeLastUsed(key *AWSAccessKey, usage *iam.AccessKeyLastUsed) {
+	if usage == nil || usage.LastUsedDate == nil {
+		return
+	}
+	key.LastUsedDate = usage.LastUsedDate
+	if usage.ServiceName != nil && *usage.ServiceName != "N/A" {
+		key.LastUsedService = *usage.ServiceName
+	}
+	if usage.Region != nil && *usage.Region != "N/A" {
+		key.LastUsedRegion = *usage.Region
 	}
 }
@@ -0,0 +1,86 @@
+package awskeystore
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"testing"
+	"time"
+)
+
+func TestInactiveKeyRetainsCreationTime(t *testing.T) {
+	created := time.Date(2021, time.July, 4, 0, 0, 0, 0, time.UTC)
+	key := internalizeKeyFromMetadata(&iam.AccessKeyMetadata{
+		AccessKeyId: aws.String("AKIA-Inactive"),
+		CreateDate:  &created,
+		Status:      aws.String(iam.StatusTypeInactive),
+	})
+
+	if key.Active() {
+		t.Error("expected key to be inactive")
+	}
+	if !key.Created().Equal(created) {
+		t.Errorf("expected creation time %s, got %s", created, key.Created())
+	}
+}
+
+func TestActiveKeyIsActive(t *testing.T) {
+	created := time.Date(2021, time.July, 4, 0, 0, 0, 0, time.UTC)
+	key := internalizeKeyFromKey(&iam.AccessKey{
+		AccessKeyId:     aws.String("AKIA-Active"),
+		CreateDate:      &created,
+		SecretAccessKey: aws.String("secret"),
+		Status:          aws.String(iam.StatusTypeActive),
+	})
+
+	if !key.Active() {
+		t.Error("expected key to be active")
+	}
+	if key.MaybeSecret() != "secret" {
+		t.Errorf("expected secret to be retained, got %q", key.MaybeSecret())
+	}
+}
+
+func TestKeyWithoutCreationTimeIsInvalid(t *testing.T) {
+	key := internalizeKeyFromMetadata(&iam.AccessKeyMetadata{
+		AccessKeyId: aws.String("AKIA-Unknown"),
+		Status:      aws.String(iam.StatusTypeActive),
+	})
+
+	if key.Created() != rotation.InvalidTime() {
+		t.Errorf("expected invalid creation time, got %s", key.Created())
+	}
+}
+
+func TestLastUsedIsRecorded(t *testing.T) {
+	used := time.Date(2021, time.July, 5, 0, 0, 0, 0, time.UTC)
+	key := &AWSAccessKey{ID: "AKIA-Used"}
+	internalizeLastUsed(key, &iam.AccessKeyLastUsed{
+		LastUsedDate: &used,
+		Region:       aws.String("us-west-2"),
+		ServiceName:  aws.String("s3"),
+	})
+
+	lastUsed, ok := key.LastUsed()
+	if !ok || !lastUsed.Equal(used) {
+		t.Errorf("expected last used %s, got %s", used, lastUsed)
+	}
+	if key.LastUsedService != "s3" || key.LastUsedRegion != "us-west-2" {
+		t.Errorf("expected s3 in us-west-2, got %s in %s", key.LastUsedService, key.LastUsedRegion)
+	}
+}
+
+func TestNeverUsedKeyHasNoUsage(t *testing.T) {
+	key := &AWSAccessKey{ID: "AKIA-Unused"}
+	internalizeLastUsed(key, &iam.AccessKeyLastUsed{
+		Region:      aws.String("N/A"),
+		ServiceName: aws.String("N/A"),
+	})
+
+	if _, ok := key.LastUsed(); ok {
+		t.Error("expected key to have never been used")
+	}
+	if key.LastUsedService != "" || key.LastUsedRegion != "" {
+		t.Errorf("expected no service or region, got %q in %q", key.LastUsedService, key.LastUsedRegion)
+	}
+}
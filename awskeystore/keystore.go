@@ -2,18 +2,47 @@ package awskeystore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/truewhitespace/key-rotation/rotation"
 )
 
+//UsageLookup controls whether ListKeys queries when each key was last used.  Queries cost an additional IAM call per
+//key and require the iam:GetAccessKeyLastUsed permission.
+type UsageLookup int
+
+const (
+	//UsageSkip never queries usage, keys report no usage.
+	UsageSkip UsageLookup = iota
+	//UsageBestEffort queries usage, treating keys as having unknown usage when permission to query is denied.
+	UsageBestEffort
+	//UsageRequired queries usage, failing to list keys when usage can not be queried.
+	UsageRequired
+)
+
+//KeyStoreOption configures optional behavior of an AWSUserKeyStore.
+type KeyStoreOption func(*AWSUserKeyStore)
+
+//WithUsageLookup sets whether keys are listed with their last use.  Defaults to UsageSkip.
+func WithUsageLookup(lookup UsageLookup) KeyStoreOption {
+	return func(a *AWSUserKeyStore) {
+		a.usage = lookup
+	}
+}
+
 //NewAWSUserKeyStore initializes a new KeyStore targeting the given user targeting the specific client.
-func NewAWSUserKeyStore(username string, client *iam.IAM) *AWSUserKeyStore {
-	return &AWSUserKeyStore{
+func NewAWSUserKeyStore(username string, client *iam.IAM, options ...KeyStoreOption) *AWSUserKeyStore {
+	store := &AWSUserKeyStore{
 		client:   client,
 		username: username,
 	}
+	for _, option := range options {
+		option(store)
+	}
+	return store
 }
 
 //AWSUserKeyStore is a bridge between AWS and rotation.KeyStore system, translating the calls into the AWS client scoped
@@ -21,26 +50,47 @@ func NewAWSUserKeyStore(username string, client *iam.IAM) *AWSUserKeyStore {
 type AWSUserKeyStore struct {
 	client   *iam.IAM
 	username string
+	usage    UsageLookup
 }
 
 func (a *AWSUserKeyStore) CreateKey(ctx context.Context) (rotation.Key, error) {
 	response, err := a.client.CreateAccessKeyWithContext(ctx, &iam.CreateAccessKeyInput{UserName: &a.username})
 	if err != nil {
-		if awsErr, ok := err.(awserr.RequestFailure); ok {
-			statusCode := awsErr.StatusCode()
-			if statusCode == 404 {
-				return nil, fmt.Errorf("no such user %q", a.username)
-			}
-		}
-		return nil, err
+		return nil, classify(rotation.OperationCreate, a.username, err)
 	}
 	return internalizeKeyFromKey(response.AccessKey), nil
 }
 
 func (a *AWSUserKeyStore) DeleteKey(ctx context.Context, key rotation.Key) error {
-	actualKey := key.(*AWSAccessKey)
-	_, err := a.client.DeleteAccessKeyWithContext(ctx, &iam.DeleteAccessKeyInput{
-		AccessKeyId: &actualKey.ID,
+	id, err := accessKeyID(key)
+	if err != nil {
+		return err
+	}
+	_, err = a.client.DeleteAccessKeyWithContext(ctx, &iam.DeleteAccessKeyInput{
+		AccessKeyId: &id,
+		UserName:    &a.username,
+	})
+	return classify(rotation.OperationDelete, a.username, err)
+}
+
+//DisableKey marks the access key as inactive, allowing it to be reactivated later.
+func (a *AWSUserKeyStore) DisableKey(ctx context.Context, key rotation.Key) error {
+	return classify(rotation.OperationDisable, a.username, a.updateStatus(ctx, key, iam.StatusTypeInactive))
+}
+
+//EnableKey marks a previously disabled access key as active.
+func (a *AWSUserKeyStore) EnableKey(ctx context.Context, key rotation.Key) error {
+	return classify(rotation.OperationEnable, a.username, a.updateStatus(ctx, key, iam.StatusTypeActive))
+}
+
+func (a *AWSUserKeyStore) updateStatus(ctx context.Context, key rotation.Key, status string) error {
+	id, err := accessKeyID(key)
+	if err != nil {
+		return err
+	}
+	_, err = a.client.UpdateAccessKeyWithContext(ctx, &iam.UpdateAccessKeyInput{
+		AccessKeyId: &id,
+		Status:      aws.String(status),
 		UserName:    &a.username,
 	})
 	return err
@@ -57,17 +107,49 @@ func (a *AWSUserKeyStore) ListKeys(ctx context.Context) (rotation.KeyList, error
 				return make(rotation.KeyList, 0), nil
 			}
 		}
-		return nil, err
+		return nil, classify(rotation.OperationList, a.username, err)
 	}
 
 	out := make([]rotation.Key, len(response.AccessKeyMetadata))
 	for i, m := range response.AccessKeyMetadata {
 		key := internalizeKeyFromMetadata(m)
+		if err := a.lookupUsage(ctx, key); err != nil {
+			return nil, err
+		}
 		out[i] = key
 	}
 	return out, nil
 }
 
+//lookupUsage records the last use of the key according to the configured UsageLookup.
+func (a *AWSUserKeyStore) lookupUsage(ctx context.Context, key *AWSAccessKey) error {
+	if a.usage == UsageSkip {
+		return nil
+	}
+	usage, err := a.client.GetAccessKeyLastUsedWithContext(ctx, &iam.GetAccessKeyLastUsedInput{
+		AccessKeyId: &key.ID,
+	})
+	if err != nil {
+		//The lookup names the key rather than the user, so a missing entity is not a missing user.
+		err = classifyCall(rotation.OperationList, a.username, false, err)
+		if a.usage == UsageBestEffort && errors.Is(err, rotation.ErrPermissionDenied) {
+			return nil
+		}
+		return fmt.Errorf("querying last use of %s: %w", key.ID, err)
+	}
+	internalizeLastUsed(key, usage.AccessKeyLastUsed)
+	return nil
+}
+
+//accessKeyID extracts the access key ID of any key identifying itself, such as keys wrapped by decorators.
+func accessKeyID(key rotation.Key) (string, error) {
+	id, ok := rotation.IDOf(key)
+	if !ok {
+		return "", fmt.Errorf("key %+v does not provide an access key ID", key)
+	}
+	return id, nil
+}
+
 func (a *AWSUserKeyStore) MaximumKeys() int {
 	return 2
 }
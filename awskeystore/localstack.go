@@ -4,25 +4,30 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"time"
 )
 
-func NewLocalstackProvider() (client *iam.IAM, err error) {
-	providers := []credentials.Provider{
+//localstackCredentials accepts the placeholder credentials of Localstack unless overridden by the environment.
+func localstackCredentials() *credentials.Credentials {
+	return credentials.NewChainCredentials([]credentials.Provider{
 		&credentials.StaticProvider{Value: credentials.Value{
 			AccessKeyID:     "test",
 			SecretAccessKey: "test",
 			SessionToken:    "",
 		}},
 		&credentials.EnvProvider{},
-	}
+	})
+}
 
+func NewLocalstackProvider() (client *iam.IAM, err error) {
 	var sess *session.Session
 	awsCfg := &aws.Config{
 		Region: aws.String("us-east-1"),
 	}
 
-	awsCfg.Credentials = credentials.NewChainCredentials(providers)
+	awsCfg.Credentials = localstackCredentials()
 	sess, err = session.NewSession(awsCfg)
 	if err != nil {
 		return
@@ -33,3 +38,25 @@ func NewLocalstackProvider() (client *iam.IAM, err error) {
 	})
 	return
 }
+
+//NewLocalstackVerifier builds a CredentialVerifier against the STS API of a local Localstack instance.
+func NewLocalstackVerifier(deadline time.Duration) (*CredentialVerifier, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+	if err != nil {
+		return nil, err
+	}
+	return NewCredentialVerifier(sess, deadline, &aws.Config{Endpoint: aws.String("http://localhost:4566")}), nil
+}
+
+//NewLocalstackLocker builds a DynamoDBLocker against a local Localstack instance.
+func NewLocalstackLocker(table string, holder string) (*DynamoDBLocker, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+	if err != nil {
+		return nil, err
+	}
+	client := dynamodb.New(sess, &aws.Config{
+		Endpoint:    aws.String("http://localhost:4566"),
+		Credentials: localstackCredentials(),
+	})
+	return NewDynamoDBLocker(client, table, holder), nil
+}
@@ -2,20 +2,23 @@ package awskeystore
 
 import (
 	"context"
+	"fmt"
 	"github.com/truewhitespace/key-rotation/rotation"
+	"time"
 )
 
 //NewOnlyValidKeys creates a new KeyStore only allowing the access key IDs specified in validKeys to be considered
-//current.  All other keys will appear to have been expired regardless of status.
+//current.  All other keys will appear to have been expired regardless of status.  Disabling and enabling keys remain
+//available for backing stores supporting them.
 func NewOnlyValidKeys(backingStore rotation.KeyStore, validKeys StringSlice) rotation.KeyStore {
-	return &onlyValidKeys{
+	return rotation.PreserveCapabilities(&onlyValidKeys{
 		KeyStoreDecorator: rotation.KeyStoreDecorator{Wrapped: backingStore},
 		validKeys:         validKeys,
-	}
+	})
 }
 
 //OnlyValidKeys is a decorator designed to file AWS access key.  Any keys not within the known set will be invalidated
-//as though the keys are expired.
+//as though the keys are expired.  Keys must implement rotation.IdentifiableKey.
 type onlyValidKeys struct {
 	rotation.KeyStoreDecorator
 	validKeys StringSlice
@@ -27,18 +30,52 @@ func (o *onlyValidKeys) ListKeys(ctx context.Context) (rotation.KeyList, error)
 		return nil, err
 	}
 
+	unidentified := keys.Filter(func(k rotation.Key) bool {
+		_, ok := rotation.IDOf(k)
+		return !ok
+	})
+	if len(unidentified) > 0 {
+		return nil, fmt.Errorf("key %+v does not provide an identity and can not be filtered", unidentified[0])
+	}
+
 	out := make(rotation.KeyList, len(keys))
 	for i, k := range keys {
-		awsKey := k.(*AWSAccessKey)
-		if o.validKeys.Contains(awsKey.ID) {
-			out[i] = awsKey
+		id, _ := rotation.IDOf(k)
+		if o.validKeys.Contains(id) {
+			out[i] = k
 		} else {
-			out[i] = &AWSAccessKey{
-				ID:      awsKey.ID,
-				Secret:  nil,
-				created: rotation.InvalidTime(),
-			}
+			out[i] = &invalidatedKey{key: k, id: id}
 		}
 	}
 	return out, nil
 }
+
+//invalidatedKey presents a key with an invalid creation time, retaining all other capabilities of the key.
+type invalidatedKey struct {
+	key rotation.Key
+	id  string
+}
+
+func (i *invalidatedKey) Created() time.Time {
+	return rotation.InvalidTime()
+}
+
+func (i *invalidatedKey) KeyID() string {
+	return i.id
+}
+
+func (i *invalidatedKey) Active() bool {
+	return rotation.IsActive(i.key)
+}
+
+func (i *invalidatedKey) LastUsed() (time.Time, bool) {
+	return rotation.LastUsedOf(i.key)
+}
+
+func (i *invalidatedKey) Description() string {
+	return rotation.DescriptionOf(i.key)
+}
+
+func (i *invalidatedKey) SecretMaterial() (string, bool) {
+	return rotation.SecretOf(i.key)
+}
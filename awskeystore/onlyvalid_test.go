@@ -102,9 +102,10 @@ func TestMixIsTreatedPorperly(t *testing.T) {
 		return
 	}
 
-	keysByID := make(map[string]*AWSAccessKey)
+	keysByID := make(map[string]rotation.Key)
 	for _, k := range keys {
-		keysByID[k.(*AWSAccessKey).ID] = k.(*AWSAccessKey)
+		id, _ := rotation.IDOf(k)
+		keysByID[id] = k
 	}
 	if keysByID[unknownKey.ID].Created() != rotation.InvalidTime() {
 		t.Errorf("exepcted invalidated key got %+v", keys[0])
@@ -115,3 +116,47 @@ func TestMixIsTreatedPorperly(t *testing.T) {
 		t.Errorf("expected key to be unmodified, got %+v", keys[0])
 	}
 }
+
+//otherKey is a key of some other binding.
+type otherKey struct {
+	id string
+}
+
+func (o *otherKey) Created() time.Time {
+	return time.Now()
+}
+
+func (o *otherKey) KeyID() string {
+	return o.id
+}
+
+func (o *otherKey) Active() bool {
+	return false
+}
+
+func TestNonAWSKeyIsInvalidated(t *testing.T) {
+	filter := newTestKeyFilter(nil, rotation.KeyList{&otherKey{id: "other"}})
+
+	keys, err := filter.ListKeys(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if keys[0].Created() != rotation.InvalidTime() {
+		t.Errorf("expected key to be set to invalid date, got %+v", keys[0])
+	}
+	if id, _ := rotation.IDOf(keys[0]); id != "other" {
+		t.Errorf("expected key to retain its ID, got %q", id)
+	}
+	if rotation.IsActive(keys[0]) {
+		t.Error("expected key to retain its status")
+	}
+}
+
+func TestOnlyValidKeysPreservesCapabilities(t *testing.T) {
+	if _, ok := newTestKeyFilter(nil, nil).(rotation.DisablingKeyStore); !ok {
+		t.Error("expected keys of an AWS user to remain disablable")
+	}
+	if _, ok := newTestKeyFilter(nil, nil).(rotation.EnablingKeyStore); !ok {
+		t.Error("expected keys of an AWS user to remain enablable")
+	}
+}
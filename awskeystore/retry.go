@@ -0,0 +1,26 @@
+package awskeystore
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"net/http"
+)
+
+//IsRetryable classifies errors from the AWS API which are transient, such as throttling, request timeouts and server
+//side failures.  Suitable for rotation.NewRetryingKeyStore.
+func IsRetryable(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	if request.IsErrorThrottle(awsErr) || request.IsErrorRetryable(awsErr) {
+		return true
+	}
+	var failure awserr.RequestFailure
+	if errors.As(err, &failure) {
+		status := failure.StatusCode()
+		return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	return false
+}
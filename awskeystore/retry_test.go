@@ -0,0 +1,29 @@
+package awskeystore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"throttling", awserr.NewRequestFailure(awserr.New("Throttling", "Rate exceeded", nil), 400, "req"), true},
+		{"server error", awserr.NewRequestFailure(awserr.New("ServiceFailure", "internal", nil), 500, "req"), true},
+		{"request timeout", awserr.NewRequestFailure(awserr.New("RequestTimeout", "timeout", nil), 408, "req"), true},
+		{"wrapped", fmt.Errorf("querying last use of AKIA: %w", awserr.NewRequestFailure(awserr.New("Throttling", "Rate exceeded", nil), 400, "req")), true},
+		{"access denied", awserr.NewRequestFailure(awserr.New("AccessDenied", "denied", nil), 403, "req"), false},
+		{"no such entity", awserr.NewRequestFailure(awserr.New("NoSuchEntity", "missing", nil), 404, "req"), false},
+		{"not aws", errors.New("no such user"), false},
+	}
+	for _, c := range cases {
+		if retryable := IsRetryable(c.err); retryable != c.retryable {
+			t.Errorf("Expected %s to be retryable %t, got %t", c.name, c.retryable, retryable)
+		}
+	}
+}
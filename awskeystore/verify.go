@@ -0,0 +1,84 @@
+package awskeystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"strings"
+	"time"
+)
+
+//CredentialVerifier confirms a newly created access key is usable by calling STS GetCallerIdentity with it.  New IAM
+//keys are eventually consistent, so failures are retried with exponential backoff until the deadline passes.
+type CredentialVerifier struct {
+	provider client.ConfigProvider
+	configs  []*aws.Config
+	//Deadline bounds the total time spent waiting for a key to become usable.
+	Deadline time.Duration
+	//Backoff determines the waits between attempts.
+	Backoff rotation.Backoff
+}
+
+//NewCredentialVerifier builds a verifier issuing STS requests through the given session.  Additional configuration,
+//such as an alternate endpoint, is applied to each STS client.  The credentials of the session are never used.
+func NewCredentialVerifier(provider client.ConfigProvider, deadline time.Duration, configs ...*aws.Config) *CredentialVerifier {
+	return &CredentialVerifier{
+		provider: provider,
+		configs:  configs,
+		Deadline: deadline,
+		Backoff:  rotation.Backoff{Initial: 500 * time.Millisecond, Maximum: 5 * time.Second, Jitter: 0.2},
+	}
+}
+
+//Verify waits until the key authenticates as the IAM user owning it.  The key must have been just created, as only
+//then is the secret known.
+func (v *CredentialVerifier) Verify(ctx context.Context, key *AWSAccessKey) error {
+	if key.Secret == nil {
+		return fmt.Errorf("unable to verify %s without its secret", key.ID)
+	}
+	configs := append(append([]*aws.Config{}, v.configs...), &aws.Config{
+		Credentials: credentials.NewStaticCredentials(key.ID, *key.Secret, ""),
+	})
+	stsClient := sts.New(v.provider, configs...)
+
+	ctx, cancel := context.WithTimeout(ctx, v.Deadline)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		identity, err := stsClient.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+		if err == nil {
+			return verifyIdentity(key, aws.StringValue(identity.Arn))
+		}
+		if v.Backoff.Wait(ctx, attempt) != nil {
+			return fmt.Errorf("key %s not usable after %d attempts: %w", key.ID, attempt, err)
+		}
+	}
+}
+
+//verifyIdentity ensures the ARN reported by STS belongs to the IAM user owning the key, allowing for user paths.
+func verifyIdentity(key *AWSAccessKey, arn string) error {
+	if key.UserName == "" {
+		return nil
+	}
+	if !strings.Contains(arn, ":user/") || !strings.HasSuffix(arn, "/"+key.UserName) {
+		return fmt.Errorf("key %s authenticated as %s, expected user %s", key.ID, arn, key.UserName)
+	}
+	return nil
+}
+
+//Hook verifies each key as it is created.  A failed verification stops the plan before any older keys are disabled or
+//destroyed.
+func (v *CredentialVerifier) Hook() rotation.Hook {
+	return &rotation.HookFuncs{
+		OnAfterCreate: func(ctx context.Context, plan *rotation.KeyRotationPlan, key rotation.Key) error {
+			awsKey, ok := key.(*AWSAccessKey)
+			if !ok {
+				return fmt.Errorf("unable to verify non-AWS key %+v", key)
+			}
+			return v.Verify(ctx, awsKey)
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package awskeystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeSTS stands in for the STS GetCallerIdentity API, rejecting credentials until enough attempts have been made.
+type fakeSTS struct {
+	lock     sync.Mutex
+	attempts int
+	//rejections is the number of attempts refused as though the key has not yet propagated.
+	rejections int
+	arn        string
+	//accessKeys are the key IDs each request was signed with.
+	accessKeys []string
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.attempts++
+	authorization := r.Header.Get("Authorization")
+	if start := strings.Index(authorization, "Credential="); start >= 0 {
+		credential := authorization[start+len("Credential="):]
+		f.accessKeys = append(f.accessKeys, credential[:strings.Index(credential, "/")])
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	if f.attempts <= f.rejections {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><Error><Type>Sender</Type><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error><RequestId>fake</RequestId></ErrorResponse>`)
+		return
+	}
+	_, _ = fmt.Fprintf(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><GetCallerIdentityResult><Arn>%s</Arn><UserId>AIDAFAKE</UserId><Account>123456789012</Account></GetCallerIdentityResult><ResponseMetadata><RequestId>fake</RequestId></ResponseMetadata></GetCallerIdentityResponse>`, f.arn)
+}
+
+func newTestVerifier(t *testing.T, fake *fakeSTS, deadline time.Duration) *CredentialVerifier {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	sess, err := session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+	if err != nil {
+		t.Fatalf("Failed to create session because %s", err.Error())
+	}
+	verifier := NewCredentialVerifier(sess, deadline, &aws.Config{
+		Endpoint:   aws.String(server.URL),
+		MaxRetries: aws.Int(0),
+	})
+	verifier.Backoff = rotation.Backoff{Initial: time.Millisecond, Maximum: 4 * time.Millisecond}
+	return verifier
+}
+
+func newCreatedKey(user string) *AWSAccessKey {
+	return &AWSAccessKey{ID: "AKIANEW", Secret: aws.String("secret"), UserName: user}
+}
+
+func TestVerifyWaitsForPropagation(t *testing.T) {
+	fake := &fakeSTS{rejections: 3, arn: "arn:aws:iam::123456789012:user/deploy/alice"}
+	verifier := newTestVerifier(t, fake, time.Second)
+
+	if err := verifier.Verify(context.Background(), newCreatedKey("alice")); err != nil {
+		t.Fatalf("Expected key to verify, got %s", err.Error())
+	}
+	if fake.attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %d", fake.attempts)
+	}
+	for _, id := range fake.accessKeys {
+		if id != "AKIANEW" {
+			t.Errorf("Expected requests signed with the new key, got %q", id)
+		}
+	}
+}
+
+func TestVerifyGivesUpAfterDeadline(t *testing.T) {
+	fake := &fakeSTS{rejections: 1 << 30, arn: "arn:aws:iam::123456789012:user/alice"}
+	verifier := newTestVerifier(t, fake, 50*time.Millisecond)
+
+	if err := verifier.Verify(context.Background(), newCreatedKey("alice")); err == nil {
+		t.Fatal("Expected verification to fail")
+	}
+	if fake.attempts < 2 {
+		t.Errorf("Expected verification to be retried, got %d attempts", fake.attempts)
+	}
+}
+
+func TestVerifyRejectsOtherIdentity(t *testing.T) {
+	fake := &fakeSTS{arn: "arn:aws:iam::123456789012:user/mallory"}
+	verifier := newTestVerifier(t, fake, time.Second)
+
+	if err := verifier.Verify(context.Background(), newCreatedKey("alice")); err == nil {
+		t.Fatal("Expected verification to reject a key belonging to another user")
+	}
+	if fake.attempts != 1 {
+		t.Errorf("Expected a mismatched identity not to be retried, got %d attempts", fake.attempts)
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"io"
+	"os"
+	"os/user"
+)
+
+type auditFlags struct {
+	file     string
+	operator string
+	//policy names the rotation policy recorded with each entry, set by the command once known.
+	policy string
+	log    *rotation.AuditLog
+}
+
+func (a *auditFlags) attach(f *pflag.FlagSet) {
+	f.StringVar(&a.file, "audit-log", "", "append a tamper evident record of every key change to this file")
+	f.StringVar(&a.operator, "operator", defaultOperator(), "identity of the person or system recorded within the audit log")
+}
+
+//defaultOperator is the local user running the command.
+func defaultOperator() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+//open verifies the existing audit log before preparing to append to it.  The log is locked from verification until
+//closed so concurrent runs sharing the log can not fork the hash chain.  The returned function reports the new head
+//of the log to out, for recording elsewhere, then closes the log.  It must be called once all changes have been made.
+func (a *auditFlags) open(policy string, out io.Writer) (func() error, error) {
+	a.policy = policy
+	if a.file == "" {
+		return func() error { return nil }, nil
+	}
+	file, err := os.OpenFile(a.file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockAuditFile(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("locking audit log %s: %w", a.file, err)
+	}
+	head, err := rotation.VerifyAuditLog(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("refusing to append to audit log %s: %w", a.file, err)
+	}
+	a.log = rotation.NewAuditLog(file, head, nil)
+	return func() error {
+		if written := a.log.Head(); written != head {
+			if _, err := fmt.Fprintf(out, "Audit log %s head is now %s after %d entries\n", a.file, written.Hash, written.Sequence); err != nil {
+				_ = file.Close()
+				return err
+			}
+		}
+		return file.Close()
+	}, nil
+}
+
+//wrap records changes to the store when an audit log has been opened.
+func (a *auditFlags) wrap(store rotation.KeyStore, target string) rotation.KeyStore {
+	if a.log == nil {
+		return store
+	}
+	return rotation.NewAuditingKeyStore(store, a.log, rotation.AuditScope{Target: target, Operator: a.operator, Policy: a.policy})
+}
+
+func verifyAuditLog(cmd *cobra.Command, args []string, expectedHead string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	head, err := rotation.VerifyAuditLog(file)
+	if err != nil {
+		return fmt.Errorf("audit log %s is not intact: %w", args[0], err)
+	}
+	if expectedHead != "" && head.Hash != expectedHead {
+		return errors.New("audit log does not end with the expected entry, it may have been truncated")
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Audit log intact with %d entries, head %s\n", head.Sequence, head.Hash)
+	return err
+}
+
+func auditVerifyCmd() *cobra.Command {
+	var expectedHead string
+	cmd := &cobra.Command{
+		Use:   "verify [file]",
+		Short: "Verifies the hash chain of an audit log has not been modified",
+		Args:  usage(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyAuditLog(cmd, args, expectedHead)
+		},
+	}
+	cmd.Flags().StringVar(&expectedHead, "head", "", "hash the final entry must have, detecting removal of trailing entries")
+	return cmd
+}
+
+func auditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspects audit logs of key changes",
+	}
+	cmd.AddCommand(auditVerifyCmd())
+	return cmd
+}
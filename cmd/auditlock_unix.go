@@ -0,0 +1,14 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+//lockAuditFile takes an exclusive lock on the audit log, blocking until other processes appending to the same log
+//have finished.  The lock is released once the file is closed.
+func lockAuditFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
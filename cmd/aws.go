@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -8,18 +9,71 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/truewhitespace/key-rotation/awskeystore"
 	"github.com/truewhitespace/key-rotation/rotation"
+	"io"
+	"os"
+	"time"
 )
 
-func updateAWSUser(cmd *cobra.Command, args []string, flags *awsFlags, rotationConfig *rotationFlags) (err error) {
+func updateAWSUsers(cmd *cobra.Command, args []string, flags *awsFlags, rotationConfig *rotationFlags, fleetConfig *fleetFlags, hookConfig *hookFlags) (err error) {
+	ctx := cmd.Context()
+
+	closeAudit, err := flags.audit.open(rotationConfig.strategy, cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeAudit(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := flags.lock.build(flags.providerType); err != nil {
+		return err
+	}
+	flags.metrics = fleetConfig.buildMetrics()
+	targets := make([]rotation.Target, len(args))
+	for i, username := range args {
+		keystore, err := flags.buildKeyStore(username, usageLookup(rotationConfig))
+		if err != nil {
+			return err
+		}
+		targets[i] = rotation.Target{Name: username, Store: keystore}
+	}
+
+	var rotator rotation.Planner
+	if rotator, err = rotationConfig.build(); err != nil {
+		return err
+	}
+
+	var hooks rotation.Hooks
+	if hooks, err = flags.buildHooks(); err != nil {
+		return err
+	}
+	executor := fleetConfig.build(rotator)
+	executor.Hooks = append(hooks, hookConfig.build()...)
+	executor.Metrics = flags.metrics
+	result := executor.Run(ctx, targets)
+	if flags.metrics != nil {
+		if err := flags.metrics.WriteTextfile(fleetConfig.metricsFile); err != nil {
+			return err
+		}
+	}
+	if err := fleetConfig.print(cmd, targets, result); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+func planAWSUser(cmd *cobra.Command, args []string, flags *awsFlags, rotationConfig *rotationFlags, outFile string) (err error) {
 	ctx := cmd.Context()
 	username := args[0]
 
-	keystore, err := flags.buildKeyStore(username)
+	keystore, err := flags.buildKeyStore(username, usageLookup(rotationConfig))
 	if err != nil {
 		return err
 	}
 
-	var rotator *rotation.GracefulExpiration
+	var rotator rotation.Planner
 	if rotator, err = rotationConfig.build(); err != nil {
 		return err
 	}
@@ -28,29 +82,371 @@ func updateAWSUser(cmd *cobra.Command, args []string, flags *awsFlags, rotationC
 	if plan, err = rotator.Plan(ctx, keystore); err != nil {
 		return err
 	}
+	plan.Target = username
+
+	if err := printPlan(cmd.OutOrStdout(), plan); err != nil {
+		return err
+	}
+	if outFile == "" {
+		return nil
+	}
+	return writePlan(outFile, plan)
+}
+
+func applyAWSPlan(cmd *cobra.Command, args []string, flags *awsFlags, hookConfig *hookFlags) (err error) {
+	ctx := cmd.Context()
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	saved := &rotation.KeyRotationPlan{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		return fmt.Errorf("reading plan %s: %w", args[0], err)
+	}
+	if saved.Target == "" {
+		return fmt.Errorf("plan %s does not name a target user", args[0])
+	}
+	if saved.Created.After(time.Now()) {
+		return fmt.Errorf("plan %s was produced as of %s which has not yet passed", args[0], saved.Created.Format(timeFormat))
+	}
+
+	closeAudit, err := flags.audit.open(saved.Policy.Name, cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeAudit(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := flags.lock.build(flags.providerType); err != nil {
+		return err
+	}
+	keystore, err := flags.buildKeyStore(saved.Target, awskeystore.UsageSkip)
+	if err != nil {
+		return err
+	}
+
 	var keys rotation.KeyList
-	if keys, err = plan.Apply(ctx, keystore); err != nil {
+	err = hold(ctx, keystore, func() error {
+		plan, err := saved.Rebind(ctx, keystore)
+		if err != nil {
+			return err
+		}
+		if plan.Hooks, err = flags.buildHooks(); err != nil {
+			return err
+		}
+		plan.Hooks = append(plan.Hooks, hookConfig.build()...)
+		keys, err = plan.Apply(ctx, keystore)
 		return err
+	})
+	if err != nil {
+		var applyErr *rotation.ApplyError
+		if errors.As(err, &applyErr) {
+			remainingFile := args[0] + ".remaining"
+			if err := writePlan(remainingFile, applyErr.Remaining); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Remaining operations saved to %s\n", remainingFile); err != nil {
+				return err
+			}
+		}
+		return reportApplyFailure(cmd.ErrOrStderr(), err)
 	}
+	if dryRun, ok := rotation.Find[*rotation.DryRunKeyStore](keystore); ok {
+		if err := printOperations(cmd.OutOrStdout(), dryRun.Operations()); err != nil {
+			return err
+		}
+	}
+	return printKeys(cmd.OutOrStdout(), saved.Target, keys)
+}
+
+//reportApplyFailure describes the changes made by a partially applied plan before returning the original error.
+func reportApplyFailure(out io.Writer, err error) error {
+	var applyErr *rotation.ApplyError
+	if !errors.As(err, &applyErr) {
+		return err
+	}
+	if _, writeErr := fmt.Fprintln(out, "Plan was only partially applied"); writeErr != nil {
+		return writeErr
+	}
+	for _, k := range applyErr.Deleted {
+		if _, writeErr := fmt.Fprintf(out, "  deleted %s\n", describeKey(k)); writeErr != nil {
+			return writeErr
+		}
+	}
+	for _, k := range applyErr.Disabled {
+		if _, writeErr := fmt.Fprintf(out, "  disabled %s\n", describeKey(k)); writeErr != nil {
+			return writeErr
+		}
+	}
+	if applyErr.Created != nil {
+		//The key exists within the store and will be considered valid, so the secret must not be lost.
+		if _, writeErr := fmt.Fprintf(out, "  created %s -- %#v\n", describeKey(applyErr.Created), describeSecret(applyErr.Created)); writeErr != nil {
+			return writeErr
+		}
+	}
+	if applyErr.Discarded != nil {
+		if _, writeErr := fmt.Fprintf(out, "  discarded new key %s\n", describeKey(applyErr.Discarded)); writeErr != nil {
+			return writeErr
+		}
+	}
+	for _, k := range applyErr.Vetoed {
+		if _, writeErr := fmt.Fprintf(out, "  retained %s, deletion vetoed by hook\n", describeKey(k)); writeErr != nil {
+			return writeErr
+		}
+	}
+	for _, k := range applyErr.Remaining.DisableKeys {
+		if _, writeErr := fmt.Fprintf(out, "  not disabled %s\n", describeKey(k)); writeErr != nil {
+			return writeErr
+		}
+	}
+	for _, k := range applyErr.Remaining.DestroyKeys {
+		if _, writeErr := fmt.Fprintf(out, "  not deleted %s\n", describeKey(k)); writeErr != nil {
+			return writeErr
+		}
+	}
+	if applyErr.Remaining.CreateKey {
+		if _, writeErr := fmt.Fprintln(out, "  not created new key"); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+func writePlan(fileName string, plan *rotation.KeyRotationPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0600)
+}
 
+func statusAWSUser(cmd *cobra.Command, args []string, flags *awsFlags, rotationConfig *rotationFlags) (err error) {
+	ctx := cmd.Context()
+	username := args[0]
+
+	keystore, err := flags.buildKeyStore(username, awskeystore.UsageBestEffort)
+	if err != nil {
+		return err
+	}
+
+	var rotator rotation.Planner
+	if rotator, err = rotationConfig.build(); err != nil {
+		return err
+	}
+
+	classifier, ok := rotator.(rotation.Classifier)
+	if !ok {
+		return fmt.Errorf("strategy %s does not support classifying keys", rotationConfig.strategy)
+	}
+	var classification rotation.Classification
+	if classification, err = classifier.Classify(ctx, keystore); err != nil {
+		return err
+	}
 	out := cmd.OutOrStdout()
 	if _, err := fmt.Fprintf(out, "Keys for %s\n", username); err != nil {
 		return err
 	}
-	for i, k := range keys {
-		awsKey := k.(*awskeystore.AWSAccessKey)
-		if _, err := fmt.Fprintf(out, "%d: %s -- %#v\n", i, awsKey.ID, awsKey.MaybeSecret()); err != nil {
+	return printClassification(out, classification)
+}
+
+func rollbackAWSUser(cmd *cobra.Command, args []string, flags *awsFlags) (err error) {
+	ctx := cmd.Context()
+	username := args[0]
+	closeAudit, err := flags.audit.open("rollback", cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeAudit(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := flags.lock.build(flags.providerType); err != nil {
+		return err
+	}
+	keystore, err := flags.buildKeyStore(username, awskeystore.UsageSkip)
+	if err != nil {
+		return err
+	}
+
+	var result *rotation.RollbackResult
+	err = hold(ctx, keystore, func() (err error) {
+		result, err = rotation.Rollback(ctx, keystore)
+		return err
+	})
+	out := cmd.OutOrStdout()
+	if result != nil {
+		if _, err := fmt.Fprintf(out, "Rolled back %s\n", username); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "  reactivated %s (created %s)\n", describeKey(result.Enabled), result.Enabled.Created().Format(timeFormat)); err != nil {
+			return err
+		}
+		if result.Disabled != nil {
+			if _, err := fmt.Fprintf(out, "  deactivated %s (created %s)\n", describeKey(result.Disabled), result.Disabled.Created().Format(timeFormat)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(cmd.ErrOrStderr(), "Pause scheduled rotations of this user, otherwise the next run will rotate away from the reactivated key"); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func printKeys(out io.Writer, target string, keys rotation.KeyList) error {
+	if _, err := fmt.Fprintf(out, "Keys for %s\n", target); err != nil {
+		return err
+	}
+	for i, k := range keys.SortByCreated() {
+		if _, err := fmt.Fprintf(out, "%d: %s -- %#v (last used %s)\n", i, describeKey(k), describeSecret(k), describeUsage(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//printOperations describes the changes recorded during a dry run.
+func printOperations(out io.Writer, operations []rotation.Operation) error {
+	if _, err := fmt.Fprintln(out, "Dry run, no changes were made.  Would have performed:"); err != nil {
+		return err
+	}
+	for _, o := range operations {
+		if _, err := fmt.Fprintf(out, "  %s %s\n", o.Kind, describeKey(o.Key)); err != nil {
+			return err
+		}
+	}
+	if len(operations) == 0 {
+		if _, err := fmt.Fprintln(out, "  nothing"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printClassification(out io.Writer, classification rotation.Classification) error {
+	for _, c := range classification.SortByCreated() {
+		transition := "never"
+		if c.Remaining > 0 {
+			transition = "in " + c.Remaining.Round(time.Second).String()
+		}
+		if _, err := fmt.Fprintf(out, "  %s: %s, age %s, next transition %s, last used %s -- %s\n", describeDetails(c.Key), c.State, c.Age.Round(time.Second), transition, describeUsage(c.Key), c.Reason); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func printPlan(out io.Writer, plan *rotation.KeyRotationPlan) error {
+	if _, err := fmt.Fprintf(out, "Plan for %s using %s\n", plan.Target, plan.Policy.Name); err != nil {
+		return err
+	}
+	if err := printClassification(out, plan.Classification); err != nil {
+		return err
+	}
+	for _, k := range plan.DisableKeys {
+		if _, err := fmt.Fprintf(out, "  disable %s (created %s)\n", describeKey(k), k.Created().Format(timeFormat)); err != nil {
+			return err
+		}
+	}
+	for _, k := range plan.DestroyKeys {
+		if _, err := fmt.Fprintf(out, "  destroy %s (created %s)\n", describeKey(k), k.Created().Format(timeFormat)); err != nil {
+			return err
+		}
+	}
+	if plan.Eviction != nil {
+		if _, err := fmt.Fprintf(out, "  evicting %s using %s policy -- %s\n", describeKey(plan.Eviction.Key), plan.Eviction.Policy, plan.Eviction.Reason); err != nil {
+			return err
+		}
+	}
+	if plan.CreateKey {
+		if _, err := fmt.Fprintln(out, "  create new key"); err != nil {
+			return err
+		}
+	}
+	if !plan.CreateDeferredUntil.IsZero() {
+		if _, err := fmt.Fprintf(out, "  creation deferred until %s\n", plan.CreateDeferredUntil.Format(timeFormat)); err != nil {
+			return err
+		}
+	}
+	if !plan.CreateKey && len(plan.DisableKeys) == 0 && len(plan.DestroyKeys) == 0 {
+		if _, err := fmt.Fprintln(out, "  no changes"); err != nil {
+			return err
+		}
+	}
+	return printWarnings(out, plan)
+}
+
 type awsFlags struct {
-	providerType string
+	providerType  string
+	attempts      int
+	verifyTimeout time.Duration
+	dryRun        bool
+	audit         auditFlags
+	lock          lockFlags
+	//metrics when set counts the changes made to each store.
+	metrics *rotation.Metrics
+}
+
+func (flags *awsFlags) attach(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flags.providerType, "aws-provider", "a", "default", "Must be either {default,localstack}")
+	cmd.Flags().IntVar(&flags.attempts, "attempts", 5, "maximum attempts of each AWS operation failing with throttling or transient errors")
+}
+
+//attachVerify adds verification of newly created keys.  Only suitable for commands which create keys.
+func (flags *awsFlags) attachVerify(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&flags.verifyTimeout, "verify-timeout", 0, "wait up to this long for new keys to authenticate with STS before retiring old keys, zero disables")
 }
 
-func (flags *awsFlags) buildKeyStore(forUser string) (result rotation.KeyStore, err error) {
+//attachDryRun adds the ability to preview changes against the live keys.  Only suitable for commands which modify keys.
+func (flags *awsFlags) attachDryRun(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "record the operations which would be performed without changing any keys")
+}
+
+//attachAudit adds recording of key changes to an audit log.  Only suitable for commands which modify keys.
+func (flags *awsFlags) attachAudit(cmd *cobra.Command) {
+	flags.audit.attach(cmd.Flags())
+}
+
+//attachLock adds locking of each target while it is changed.  Only suitable for commands which modify keys.
+func (flags *awsFlags) attachLock(cmd *cobra.Command) {
+	flags.lock.attach(cmd.Flags())
+}
+
+//buildHooks produces the hooks required by the AWS specific flags.  New keys are not verified during a dry run as they
+//do not exist.
+func (flags *awsFlags) buildHooks() (rotation.Hooks, error) {
+	if flags.verifyTimeout <= 0 || flags.dryRun {
+		return nil, nil
+	}
+	var verifier *awskeystore.CredentialVerifier
+	if flags.providerType == "default" {
+		verifier = awskeystore.NewCredentialVerifier(session.Must(session.NewSession()), flags.verifyTimeout)
+	} else if flags.providerType == "localstack" {
+		var err error
+		if verifier, err = awskeystore.NewLocalstackVerifier(flags.verifyTimeout); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("bad aws provider type " + flags.providerType)
+	}
+	return rotation.Hooks{verifier.Hook()}, nil
+}
+
+//usageLookup queries when keys were last used only when the rotation policy relies upon it.
+func usageLookup(config *rotationFlags) awskeystore.UsageLookup {
+	if config.needsUsage() {
+		return awskeystore.UsageRequired
+	}
+	return awskeystore.UsageSkip
+}
+
+func (flags *awsFlags) buildKeyStore(forUser string, usage awskeystore.UsageLookup) (result rotation.KeyStore, err error) {
 	var awsClient *iam.IAM
 	if flags.providerType == "default" {
 		sess := session.Must(session.NewSession())
@@ -63,22 +459,119 @@ func (flags *awsFlags) buildKeyStore(forUser string) (result rotation.KeyStore,
 	} else {
 		return nil, errors.New("bad aws provider type " + flags.providerType)
 	}
-	result = awskeystore.NewAWSUserKeyStore(forUser, awsClient)
+	result = rotation.NewTracingKeyStore(awskeystore.NewAWSUserKeyStore(forUser, awsClient, awskeystore.WithUsageLookup(usage)), forUser)
+	if flags.attempts > 1 {
+		result = rotation.NewRetryingKeyStore(result, awskeystore.IsRetryable, rotation.WithAttempts(flags.attempts))
+	}
+	result = flags.audit.wrap(result, forUser)
+	if flags.metrics != nil {
+		result = rotation.NewMetricsKeyStore(result, flags.metrics, forUser)
+	}
+	if flags.dryRun {
+		//Dry runs make no changes and so never need to take the lock.
+		result = rotation.NewDryRunKeyStore(result, nil)
+	} else {
+		result = flags.lock.wrap(result, forUser)
+	}
 	return
 }
 
+func awsPlanCmd() *cobra.Command {
+	flags := &awsFlags{}
+	config := &rotationFlags{}
+	var outFile string
+	cmd := &cobra.Command{
+		Use:   "plan [user]",
+		Short: "Plans the rotation of the specified AWS user without making changes",
+		Args:  usage(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return planAWSUser(cmd, args, flags, config, outFile)
+		},
+	}
+	flags.attach(cmd)
+	cmd.Flags().StringVarP(&outFile, "out", "o", "", "file to persist the plan to for a later apply")
+	config.attach(cmd.Flags())
+	config.attachAsOf(cmd.Flags())
+	return cmd
+}
+
+func awsStatusCmd() *cobra.Command {
+	flags := &awsFlags{}
+	config := &rotationFlags{}
+	cmd := &cobra.Command{
+		Use:   "status [user]",
+		Short: "Reports the state of each key for the specified AWS user",
+		Args:  usage(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return statusAWSUser(cmd, args, flags, config)
+		},
+	}
+	flags.attach(cmd)
+	config.attach(cmd.Flags())
+	config.attachAsOf(cmd.Flags())
+	return cmd
+}
+
+func awsRollbackCmd() *cobra.Command {
+	flags := &awsFlags{}
+	cmd := &cobra.Command{
+		Use:   "rollback [user]",
+		Short: "Reactivates the previously disabled key of the AWS user and deactivates the newest key",
+		Args:  usage(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rollbackAWSUser(cmd, args, flags)
+		},
+	}
+	flags.attach(cmd)
+	flags.attachAudit(cmd)
+	flags.attachLock(cmd)
+	return cmd
+}
+
+func awsApplyCmd() *cobra.Command {
+	flags := &awsFlags{}
+	hooks := &hookFlags{}
+	cmd := &cobra.Command{
+		Use:   "apply [planfile]",
+		Short: "Applies a previously saved plan if the AWS user's keys have not changed since planning",
+		Args:  usage(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return applyAWSPlan(cmd, args, flags, hooks)
+		},
+	}
+	flags.attach(cmd)
+	flags.attachVerify(cmd)
+	flags.attachDryRun(cmd)
+	flags.attachAudit(cmd)
+	flags.attachLock(cmd)
+	hooks.attach(cmd.Flags())
+	return cmd
+}
+
 func awsCmd() *cobra.Command {
 	flags := &awsFlags{}
 	config := &rotationFlags{}
+	fleet := &fleetFlags{}
+	hooks := &hookFlags{}
 	cmd := &cobra.Command{
-		Use:     "aws [user]",
-		Short:   "Rotates the specified AWS user",
-		PreRunE: cobra.ExactArgs(1),
+		Use:     "aws [user...]",
+		Short:   "Rotates the specified AWS users",
+		PreRunE: usage(cobra.MinimumNArgs(1)),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return updateAWSUser(cmd, args, flags, config)
+			return updateAWSUsers(cmd, args, flags, config, fleet, hooks)
 		},
 	}
-	cmd.Flags().StringVarP(&flags.providerType, "aws-provider", "a", "default", "Must be either {default,moto}")
+	flags.attach(cmd)
+	flags.attachVerify(cmd)
+	flags.attachDryRun(cmd)
+	flags.attachAudit(cmd)
+	flags.attachLock(cmd)
 	config.attach(cmd.Flags())
+	fleet.attach(cmd.Flags())
+	hooks.attach(cmd.Flags())
+	cmd.AddCommand(awsPlanCmd())
+	cmd.AddCommand(awsApplyCmd())
+	cmd.AddCommand(awsStatusCmd())
+	cmd.AddCommand(awsRollbackCmd())
 	return cmd
 }
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"github.com/truewhitespace/key-rotation/rotation"
+)
+
+//Exit codes of the CLI, allowing automation to react to the cause of a failure.  Documented within the README; values
+//must not change once released.
+const (
+	ExitOK               = 0
+	ExitFailure          = 1
+	ExitUsage            = 2
+	ExitNoCapacity       = 3
+	ExitTargetNotFound   = 4
+	ExitPermissionDenied = 5
+	ExitThrottled        = 6
+	ExitStalePlan        = 7
+	ExitLocked           = 8
+)
+
+//usageError reports a command invoked with bad flags or arguments.
+type usageError struct {
+	err error
+}
+
+func (u *usageError) Error() string {
+	return u.err.Error()
+}
+
+func (u *usageError) Unwrap() error {
+	return u.err
+}
+
+//usage marks failures of the argument validator as usage errors.
+func usage(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &usageError{err}
+		}
+		return nil
+	}
+}
+
+//ExitCode maps the error returned by a command to the exit code of the process.  Failures of many targets are
+//reported by the cause of the first.
+func ExitCode(err error) int {
+	var usageErr *usageError
+	var lockErr *rotation.LockHeldError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &lockErr):
+		return ExitLocked
+	case errors.Is(err, rotation.ErrStalePlan):
+		return ExitStalePlan
+	case errors.Is(err, rotation.ErrTargetNotFound):
+		return ExitTargetNotFound
+	case errors.Is(err, rotation.ErrPermissionDenied):
+		return ExitPermissionDenied
+	case errors.Is(err, rotation.ErrNoCapacity):
+		return ExitNoCapacity
+	case errors.Is(err, rotation.ErrThrottled):
+		return ExitThrottled
+	default:
+		return ExitFailure
+	}
+}
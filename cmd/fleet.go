@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type fleetFlags struct {
+	workers     int
+	timeout     time.Duration
+	output      string
+	metricsFile string
+}
+
+func (f *fleetFlags) attach(flags *pflag.FlagSet) {
+	flags.IntVar(&f.workers, "workers", 4, "maximum number of users rotated at once")
+	flags.DurationVar(&f.timeout, "timeout", 0, "maximum time spent rotating each user, zero for no limit")
+	flags.StringVar(&f.output, "output", "text", "output format, one of {text,json}")
+	flags.StringVar(&f.metricsFile, "metrics-textfile", "", "write Prometheus metrics to this file for the node exporter textfile collector")
+}
+
+//buildMetrics creates the metrics collection when metrics have been requested.
+func (f *fleetFlags) buildMetrics() *rotation.Metrics {
+	if f.metricsFile == "" {
+		return nil
+	}
+	return rotation.NewMetrics()
+}
+
+func (f *fleetFlags) build(planner rotation.Planner) *rotation.Executor {
+	return &rotation.Executor{
+		Planner: planner,
+		Workers: f.workers,
+		Timeout: f.timeout,
+	}
+}
+
+func (f *fleetFlags) print(cmd *cobra.Command, targets []rotation.Target, result *rotation.FleetResult) error {
+	dryRuns := make(map[string]*rotation.DryRunKeyStore)
+	for _, t := range targets {
+		if dryRun, ok := rotation.Find[*rotation.DryRunKeyStore](t.Store); ok {
+			dryRuns[t.Name] = dryRun
+		}
+	}
+	switch f.output {
+	case "text":
+		return printFleetText(cmd, result, dryRuns)
+	case "json":
+		return printFleetJSON(cmd.OutOrStdout(), result, dryRuns)
+	default:
+		return fmt.Errorf("bad output format %q", f.output)
+	}
+}
+
+func printFleetText(cmd *cobra.Command, result *rotation.FleetResult, dryRuns map[string]*rotation.DryRunKeyStore) error {
+	out := cmd.OutOrStdout()
+	for _, r := range result.Results {
+		if r.Plan != nil {
+			if err := printWarnings(cmd.ErrOrStderr(), r.Plan); err != nil {
+				return err
+			}
+		}
+		if r.Outcome == rotation.OutcomeFailed {
+			if _, err := fmt.Fprintf(cmd.ErrOrStderr(), "Failed rotating %s: %s\n", r.Target, r.Err.Error()); err != nil {
+				return err
+			}
+			if err := reportApplyFailure(cmd.ErrOrStderr(), r.Err); err != r.Err {
+				return err
+			}
+			continue
+		}
+		if dryRun, ok := dryRuns[r.Target]; ok {
+			if err := printOperations(out, dryRun.Operations()); err != nil {
+				return err
+			}
+		}
+		if err := printKeys(out, r.Target, r.Keys); err != nil {
+			return err
+		}
+	}
+
+	table := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "TARGET\tOUTCOME\tCREATED\tDISABLED\tDESTROYED\tDURATION\tERROR"); err != nil {
+		return err
+	}
+	for _, r := range result.Results {
+		//Count the changes actually made, which exclude vetoed deletions and include those made before a failure.
+		created, disabled, destroyed := 0, len(r.Disabled), len(r.Deleted)
+		if r.Created != nil {
+			created = 1
+		}
+		message := ""
+		if r.Err != nil {
+			message = r.Err.Error()
+		}
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", r.Target, r.Outcome, created, disabled, destroyed, r.Duration.Round(time.Millisecond), message); err != nil {
+			return err
+		}
+	}
+	return table.Flush()
+}
+
+type fleetTargetReport struct {
+	Target   string                    `json:"target"`
+	Outcome  rotation.Outcome          `json:"outcome"`
+	Error    string                    `json:"error,omitempty"`
+	Duration string                    `json:"duration"`
+	Plan     *rotation.KeyRotationPlan `json:"plan,omitempty"`
+	Keys     []fleetKeyReport          `json:"keys,omitempty"`
+	//DryRun are the operations which would have been performed, present only for dry runs.
+	DryRun []fleetOperationReport `json:"dry_run,omitempty"`
+}
+
+type fleetOperationReport struct {
+	Operation rotation.OperationKind `json:"operation"`
+	ID        string                 `json:"id"`
+}
+
+type fleetKeyReport struct {
+	ID      string    `json:"id"`
+	Secret  *string   `json:"secret,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func printFleetJSON(out io.Writer, result *rotation.FleetResult, dryRuns map[string]*rotation.DryRunKeyStore) error {
+	reports := make([]fleetTargetReport, len(result.Results))
+	for i, r := range result.Results {
+		report := fleetTargetReport{
+			Target:   r.Target,
+			Outcome:  r.Outcome,
+			Duration: r.Duration.String(),
+			Plan:     r.Plan,
+		}
+		if r.Err != nil {
+			report.Error = r.Err.Error()
+		}
+		keys := r.Keys
+		var applyErr *rotation.ApplyError
+		if errors.As(r.Err, &applyErr) && applyErr.Created != nil {
+			//Report the secret of a key created before the failure, as the key remains within the store.
+			keys = rotation.KeyList{applyErr.Created}
+		}
+		for _, k := range keys.SortByCreated() {
+			report.Keys = append(report.Keys, fleetKeyReport{ID: describeKey(k), Secret: secretOf(k), Created: k.Created()})
+		}
+		if dryRun, ok := dryRuns[r.Target]; ok {
+			for _, o := range dryRun.Operations() {
+				report.DryRun = append(report.DryRun, fleetOperationReport{Operation: o.Kind, ID: describeKey(o.Key)})
+			}
+		}
+		reports[i] = report
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
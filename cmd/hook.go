@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/pflag"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"os"
+	"os/exec"
+	"time"
+)
+
+//hookEventVariable names the environment variable holding the event passed to hook commands.
+const hookEventVariable = "KEY_ROTATION_EVENT"
+
+type hookFlags struct {
+	commands []string
+}
+
+func (h *hookFlags) attach(f *pflag.FlagSet) {
+	f.StringArrayVar(&h.commands, "hook-command", nil, "shell command run before and after each operation with the event as JSON on stdin, may be repeated")
+}
+
+func (h *hookFlags) build() rotation.Hooks {
+	hooks := make(rotation.Hooks, len(h.commands))
+	for i, command := range h.commands {
+		hooks[i] = &commandHook{command: command}
+	}
+	return hooks
+}
+
+//hookEvent is the document written to the standard input of hook commands.
+type hookEvent struct {
+	Event  string         `json:"event"`
+	Target string         `json:"target"`
+	Key    *hookEventKey  `json:"key,omitempty"`
+	Keys   []hookEventKey `json:"keys,omitempty"`
+}
+
+type hookEventKey struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Secret  *string   `json:"secret,omitempty"`
+}
+
+func newHookEventKey(k rotation.Key) hookEventKey {
+	return hookEventKey{ID: describeKey(k), Created: k.Created(), Secret: secretOf(k)}
+}
+
+//commandHook runs a shell command for each event.  A command failing before a key is deleted vetoes the deletion,
+//otherwise a failing command fails the apply.
+type commandHook struct {
+	command string
+}
+
+func (c *commandHook) run(ctx context.Context, event hookEvent) error {
+	input, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", c.command)
+	cmd.Env = append(os.Environ(), hookEventVariable+"="+event.Event)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook %q on %s: %w", c.command, event.Event, err)
+	}
+	return nil
+}
+
+func (c *commandHook) BeforeCreate(ctx context.Context, plan *rotation.KeyRotationPlan) error {
+	return c.run(ctx, hookEvent{Event: "before-create", Target: plan.Target})
+}
+
+func (c *commandHook) AfterCreate(ctx context.Context, plan *rotation.KeyRotationPlan, key rotation.Key) error {
+	entry := newHookEventKey(key)
+	return c.run(ctx, hookEvent{Event: "after-create", Target: plan.Target, Key: &entry})
+}
+
+func (c *commandHook) BeforeDelete(ctx context.Context, plan *rotation.KeyRotationPlan, key rotation.Key) error {
+	entry := newHookEventKey(key)
+	if err := c.run(ctx, hookEvent{Event: "before-delete", Target: plan.Target, Key: &entry}); err != nil {
+		return fmt.Errorf("%w: %s", rotation.ErrVetoed, err.Error())
+	}
+	return nil
+}
+
+func (c *commandHook) AfterApply(ctx context.Context, plan *rotation.KeyRotationPlan, keys rotation.KeyList) error {
+	event := hookEvent{Event: "after-apply", Target: plan.Target, Keys: make([]hookEventKey, len(keys))}
+	for i, k := range keys {
+		event.Keys[i] = newHookEventKey(k)
+	}
+	return c.run(ctx, event)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/spf13/pflag"
+	"github.com/truewhitespace/key-rotation/awskeystore"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type lockFlags struct {
+	backend   string
+	directory string
+	table     string
+	lease     time.Duration
+	locker    rotation.Locker
+}
+
+func (l *lockFlags) attach(f *pflag.FlagSet) {
+	f.StringVar(&l.backend, "lock", "file", "prevent concurrent rotations of a target using locks, one of {none,file,dynamodb}")
+	f.StringVar(&l.directory, "lock-dir", filepath.Join(os.TempDir(), "key-rotation-locks"), "directory holding lock files with --lock file")
+	f.StringVar(&l.table, "lock-table", awskeystore.DefaultLockTable, "DynamoDB table holding locks with --lock dynamodb")
+	f.DurationVar(&l.lease, "lock-lease", 15*time.Minute, "time after which an unreleased lock may be taken over, must exceed the time to rotate a target")
+}
+
+//holder describes this process within locks.
+func holder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s@%s (pid %d)", defaultOperator(), host, os.Getpid())
+}
+
+//build prepares the configured locker, nil when locking is disabled.
+func (l *lockFlags) build(providerType string) error {
+	switch l.backend {
+	case "none":
+		l.locker = nil
+	case "file":
+		l.locker = rotation.NewFileLocker(l.directory, holder())
+	case "dynamodb":
+		if providerType == "default" {
+			l.locker = awskeystore.NewDynamoDBLocker(dynamodb.New(session.Must(session.NewSession())), l.table, holder())
+		} else if providerType == "localstack" {
+			locker, err := awskeystore.NewLocalstackLocker(l.table, holder())
+			if err != nil {
+				return err
+			}
+			l.locker = locker
+		} else {
+			return fmt.Errorf("bad aws provider type %s", providerType)
+		}
+	default:
+		return fmt.Errorf("bad lock backend %q", l.backend)
+	}
+	return nil
+}
+
+//wrap guards changes to the store with the configured locker.
+func (l *lockFlags) wrap(store rotation.KeyStore, target string) rotation.KeyStore {
+	if l.locker == nil {
+		return store
+	}
+	return rotation.NewLockingKeyStore(store, l.locker, target, l.lease)
+}
+
+//hold locks the store, if lockable, for the duration of the function.
+func hold(ctx context.Context, store rotation.KeyStore, f func() error) (err error) {
+	lockable, ok := rotation.Find[rotation.Lockable](store)
+	if !ok {
+		return f()
+	}
+	if err := lockable.Lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lockable.Unlock(context.WithoutCancel(ctx)); err == nil && unlockErr != nil {
+			err = fmt.Errorf("releasing lock: %w", unlockErr)
+		}
+	}()
+	return f()
+}
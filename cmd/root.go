@@ -1,33 +1,152 @@
 package cmd
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/truewhitespace/key-rotation/rotation"
+	"io"
 	"time"
 )
 
+//timeFormat is the layout used when rendering times for humans.
+const timeFormat = time.RFC3339
+
+//describeKey renders the identity of a key for humans.
+func describeKey(k rotation.Key) string {
+	if id, ok := rotation.IDOf(k); ok {
+		return id
+	}
+	return "{unknown}"
+}
+
+//describeDetails renders the identity of a key along with any description the key provides for humans.
+func describeDetails(k rotation.Key) string {
+	if description := rotation.DescriptionOf(k); description != "" {
+		return fmt.Sprintf("%s (%s)", describeKey(k), description)
+	}
+	return describeKey(k)
+}
+
+//describeUsage renders when a key was last used for humans.
+func describeUsage(k rotation.Key) string {
+	if _, ok := k.(rotation.UsageReportingKey); !ok {
+		return "unknown"
+	}
+	used, ok := rotation.LastUsedOf(k)
+	if !ok {
+		return "never"
+	}
+	return used.Format(timeFormat)
+}
+
+//describeSecret renders the secret of a key for humans.
+func describeSecret(k rotation.Key) string {
+	if secret, ok := rotation.SecretOf(k); ok {
+		return secret
+	}
+	return "{unknown}"
+}
+
+//secretOf extracts the secret of a key for machine readable output, nil when unknown.
+func secretOf(k rotation.Key) *string {
+	if secret, ok := rotation.SecretOf(k); ok {
+		return &secret
+	}
+	return nil
+}
+
+//printWarnings reports concerns raised while planning.
+func printWarnings(out io.Writer, plan *rotation.KeyRotationPlan) error {
+	for _, warning := range plan.Warnings {
+		if _, err := fmt.Fprintf(out, "WARNING: %s\n", warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type rotationFlags struct {
+	strategy     string
 	validFor     time.Duration
 	expiresAfter time.Duration
+	quarantine   time.Duration
+	usageWindow  time.Duration
+	usageGuard   string
+	minInterval  time.Duration
+	maxDestroy   int
+	eviction     string
+	asOf         string
 }
 
 func (r *rotationFlags) attach(f *pflag.FlagSet) {
+	f.StringVar(&r.strategy, "strategy", rotation.GracefulExpirationStrategy, fmt.Sprintf("rotation strategy, one of %q", rotation.PlannerNames()))
 	f.DurationVar(&r.validFor, "valid-for", 20*24*time.Hour, "how long a key should be considered valid and usable")
 	f.DurationVar(&r.expiresAfter, "expires-after", 10*24*time.Hour, "grace period before deletion after validity")
+	f.DurationVar(&r.quarantine, "quarantine", 0, "disable expired keys for this long before deletion, zero deletes immediately")
+	f.DurationVar(&r.usageWindow, "usage-window", 0, "protect grace keys used within this window from destruction, zero disables")
+	f.StringVar(&r.usageGuard, "usage-guard", string(rotation.UsageGuardRefuse), "protection for recently used grace keys, one of {refuse,warn}")
+	f.DurationVar(&r.minInterval, "min-creation-interval", 0, "minimum age of the newest key before creating another, zero disables")
+	f.IntVar(&r.maxDestroy, "max-destructive", 0, "refuse plans disabling or destroying more keys than this for any one user, zero disables")
+	f.StringVar(&r.eviction, "evict", rotation.EvictOldest.Name(), "grace key to destroy when at capacity, one of {oldest,newest,least-recently-used,refuse}")
+}
+
+//attachAsOf adds the ability to evaluate keys at a time other than now.  Only suitable for commands which do not
+//modify keys.
+func (r *rotationFlags) attachAsOf(f *pflag.FlagSet) {
+	f.StringVar(&r.asOf, "as-of", "", "evaluate keys as of the given RFC3339 time instead of now, such as 2026-11-01T00:00:00Z")
 }
 
-func (r *rotationFlags) build() (*rotation.GracefulExpiration, error) {
-	grace := r.validFor
-	expiry := r.expiresAfter + r.validFor
-	return rotation.NewGracefulExpiration(expiry, grace)
+//needsUsage determines if the configured policy decides upon when keys were last used.
+func (r *rotationFlags) needsUsage() bool {
+	return r.usageWindow > 0 || r.eviction == rotation.EvictLeastRecentlyUsed.Name()
+}
+
+func (r *rotationFlags) build() (rotation.Planner, error) {
+	eviction, err := rotation.EvictionPolicyByName(r.eviction)
+	if err != nil {
+		return nil, err
+	}
+	usageGuard, err := rotation.ParseUsageGuard(r.usageGuard)
+	if err != nil {
+		return nil, err
+	}
+	settings := rotation.PlannerSettings{
+		MaximumAge:  r.expiresAfter + r.validFor,
+		GraceAge:    r.validFor,
+		Eviction:    eviction,
+		Quarantine:  r.quarantine,
+		UsageWindow: r.usageWindow,
+		UsageGuard:  usageGuard,
+
+		MinimumCreationInterval:      r.minInterval,
+		MaximumDestructiveOperations: r.maxDestroy,
+	}
+	if r.asOf != "" {
+		asOf, err := time.Parse(time.RFC3339, r.asOf)
+		if err != nil {
+			return nil, fmt.Errorf("bad --as-of time: %w", err)
+		}
+		settings.Clock = rotation.FixedClock(asOf)
+	}
+	return rotation.NewPlanner(r.strategy, settings)
 }
 
 func NewRoot() *cobra.Command {
+	tracing := &tracingFlags{}
 	cmd := &cobra.Command{
 		Use:   "key-rotation",
 		Short: "Plans or updates key changes for various systems",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return tracing.start(cmd)
+		},
 	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err}
+	})
+	tracing.attach(cmd.PersistentFlags())
+	cobra.OnFinalize(tracing.finish)
 	cmd.AddCommand(awsCmd())
+	cmd.AddCommand(auditCmd())
 	return cmd
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"github.com/truewhitespace/key-rotation/rotation/oteltracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"time"
+)
+
+//shutdownTimeout bounds the time spent flushing spans once a command completes.
+const shutdownTimeout = 5 * time.Second
+
+type tracingFlags struct {
+	exporter string
+	provider *sdktrace.TracerProvider
+	span     trace.Span
+}
+
+func (t *tracingFlags) attach(f *pflag.FlagSet) {
+	f.StringVar(&t.exporter, "trace-exporter", "none", "export OpenTelemetry spans, one of {none,stdout,otlp}; otlp is configured by the standard OTEL_EXPORTER_OTLP_* variables")
+}
+
+//start registers the exporter and begins a span covering the command.  The command's context carries the span so all
+//spans produced while running the command are its children.
+func (t *tracingFlags) start(cmd *cobra.Command) error {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch t.exporter {
+	case "none":
+		return nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(cmd.ErrOrStderr()), stdouttrace.WithPrettyPrint())
+	case "otlp":
+		exporter, err = otlptracehttp.New(cmd.Context())
+	default:
+		return fmt.Errorf("bad trace exporter %q", t.exporter)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "key-rotation"))),
+	)
+	rotation.SetTracer(oteltracing.NewTracer(t.provider))
+	ctx, span := t.provider.Tracer("github.com/truewhitespace/key-rotation/cmd").Start(cmd.Context(), cmd.CommandPath())
+	t.span = span
+	cmd.SetContext(ctx)
+	return nil
+}
+
+//finish ends the command's span and flushes all spans to the exporter.
+func (t *tracingFlags) finish() {
+	if t.provider == nil {
+		return
+	}
+	t.span.End()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := t.provider.Shutdown(ctx); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "WARNING: failed to export traces: %s\n", err.Error())
+	}
+}
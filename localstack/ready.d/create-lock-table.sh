@@ -0,0 +1,7 @@
+#!/bin/sh
+# Creates the table used by --lock dynamodb once Localstack is ready.
+awslocal dynamodb create-table \
+  --table-name key-rotation-locks \
+  --attribute-definitions AttributeName=target,AttributeType=S \
+  --key-schema AttributeName=target,KeyType=HASH \
+  --billing-mode PAY_PER_REQUEST
@@ -13,6 +13,6 @@ func main() {
 		if _, err := fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error()); err != nil {
 			panic(err)
 		}
-		os.Exit(-1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -0,0 +1,36 @@
+package memstore
+
+import (
+	"errors"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"time"
+)
+
+//errThrottled is the underlying error of throttled operations.
+var errThrottled = errors.New("rate exceeded")
+
+//FailNth causes the nth invocation of the operation from now to fail with err, counting from one.  For example
+//FailNth(rotation.OperationCreate, 1, err) fails the next key creation.  The failing invocation makes no changes.
+func (s *Store) FailNth(operation rotation.OperationKind, n int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failures[operation] == nil {
+		s.failures[operation] = make(map[int]error)
+	}
+	s.failures[operation][s.calls[operation]+n] = err
+}
+
+//Throttle causes the next times invocations of the operation to fail as throttled, matching rotation.ErrThrottled.
+func (s *Store) Throttle(operation rotation.OperationKind, times int) {
+	for n := 1; n <= times; n++ {
+		s.FailNth(operation, n, s.fail(operation, rotation.ErrThrottled, errThrottled))
+	}
+}
+
+//SetLatency delays every subsequent operation by the given duration.  Operations whose context ends while delayed
+//fail with the error of the context without making changes.
+func (s *Store) SetLatency(latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.latency = latency
+}
@@ -0,0 +1,46 @@
+package memstore
+
+import (
+	"time"
+)
+
+//Key is a key held by a Store.  Keys returned by CreateKey carry their secret, keys returned by ListKeys do not.
+type Key struct {
+	//ID identifies the key within the store.
+	ID string
+	//Secret is the generated secret of the key, empty once listed.
+	Secret   string
+	created  time.Time
+	inactive bool
+	lastUsed time.Time
+}
+
+func (k *Key) Created() time.Time {
+	return k.created
+}
+
+func (k *Key) KeyID() string {
+	return k.ID
+}
+
+//Active is false once the key has been disabled.
+func (k *Key) Active() bool {
+	return !k.inactive
+}
+
+//LastUsed is when the key was last marked as used through Store.MarkUsed.
+func (k *Key) LastUsed() (time.Time, bool) {
+	return k.lastUsed, !k.lastUsed.IsZero()
+}
+
+//SecretMaterial is the secret of the key, only known when the key was returned by CreateKey.
+func (k *Key) SecretMaterial() (string, bool) {
+	return k.Secret, k.Secret != ""
+}
+
+//listed produces a copy of the key as revealed by listing, without the secret.
+func (k *Key) listed() *Key {
+	out := *k
+	out.Secret = ""
+	return &out
+}
@@ -0,0 +1,241 @@
+//Package memstore provides an in-memory rotation.KeyStore for testing integrations with the rotation package without
+//access to a real key store.  Failures such as throttling, slow responses, and errors on a specific call may be
+//programmed to exercise error handling.
+package memstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"sync"
+	"time"
+)
+
+//DefaultCapacity is the number of keys a Store permits unless configured otherwise, matching AWS IAM users.
+const DefaultCapacity = 2
+
+//ErrNoSuchKey is reported when operating upon a key which is not within the store, such as a key already deleted.
+var ErrNoSuchKey = errors.New("no such key")
+
+//Option configures optional behavior of a Store.
+type Option func(*Store)
+
+//WithCapacity sets the maximum number of keys, reported through MaximumKeys and enforced by CreateKey.  Defaults to
+//DefaultCapacity.
+func WithCapacity(capacity int) Option {
+	return func(s *Store) {
+		s.capacity = capacity
+	}
+}
+
+//WithClock sets the source of creation times for new keys.  Defaults to rotation.SystemClock.
+func WithClock(clock rotation.Clock) Option {
+	return func(s *Store) {
+		s.clock = clock
+	}
+}
+
+//WithTarget names the target reported within errors of the store.  Defaults to "memory".
+func WithTarget(target string) Option {
+	return func(s *Store) {
+		s.target = target
+	}
+}
+
+//WithLatency delays every operation by the given duration, as SetLatency.
+func WithLatency(latency time.Duration) Option {
+	return func(s *Store) {
+		s.latency = latency
+	}
+}
+
+//New creates an empty Store.
+func New(options ...Option) *Store {
+	store := &Store{
+		capacity: DefaultCapacity,
+		clock:    rotation.SystemClock,
+		target:   "memory",
+		calls:    make(map[rotation.OperationKind]int),
+		failures: make(map[rotation.OperationKind]map[int]error),
+	}
+	for _, option := range options {
+		option(store)
+	}
+	return store
+}
+
+//Store is an in-memory rotation.KeyStore supporting disabling and enabling keys.  Keys are created with a generated ID
+//and secret at the time reported by the configured clock.  Safe for concurrent use.
+type Store struct {
+	lock     sync.Mutex
+	capacity int
+	clock    rotation.Clock
+	target   string
+	latency  time.Duration
+	keys     []*Key
+	issued   int
+	//calls counts the invocations of each operation.
+	calls map[rotation.OperationKind]int
+	//failures are the errors programmed for specific invocations of each operation.
+	failures map[rotation.OperationKind]map[int]error
+}
+
+func (s *Store) CreateKey(ctx context.Context) (rotation.Key, error) {
+	if err := s.begin(ctx, rotation.OperationCreate); err != nil {
+		return nil, err
+	}
+	defer s.lock.Unlock()
+
+	if len(s.keys) >= s.capacity {
+		return nil, s.fail(rotation.OperationCreate, rotation.ErrNoCapacity, fmt.Errorf("at maximum of %d keys", s.capacity))
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, s.fail(rotation.OperationCreate, nil, err)
+	}
+	s.issued++
+	key := &Key{
+		ID:      fmt.Sprintf("MEMKEY%06d", s.issued),
+		Secret:  secret,
+		created: s.clock.Now(),
+	}
+	s.keys = append(s.keys, key.listed())
+	return key, nil
+}
+
+func (s *Store) DeleteKey(ctx context.Context, key rotation.Key) error {
+	if err := s.begin(ctx, rotation.OperationDelete); err != nil {
+		return err
+	}
+	defer s.lock.Unlock()
+
+	i, err := s.find(rotation.OperationDelete, key)
+	if err != nil {
+		return err
+	}
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	return nil
+}
+
+//DisableKey marks the key as inactive, retaining it within the store.
+func (s *Store) DisableKey(ctx context.Context, key rotation.Key) error {
+	return s.setActive(ctx, rotation.OperationDisable, key, false)
+}
+
+//EnableKey marks a previously disabled key as active.
+func (s *Store) EnableKey(ctx context.Context, key rotation.Key) error {
+	return s.setActive(ctx, rotation.OperationEnable, key, true)
+}
+
+func (s *Store) setActive(ctx context.Context, operation rotation.OperationKind, key rotation.Key, active bool) error {
+	if err := s.begin(ctx, operation); err != nil {
+		return err
+	}
+	defer s.lock.Unlock()
+
+	i, err := s.find(operation, key)
+	if err != nil {
+		return err
+	}
+	s.keys[i].inactive = !active
+	return nil
+}
+
+//ListKeys lists the keys in the order created.  Secrets are not revealed.
+func (s *Store) ListKeys(ctx context.Context) (rotation.KeyList, error) {
+	if err := s.begin(ctx, rotation.OperationList); err != nil {
+		return nil, err
+	}
+	defer s.lock.Unlock()
+
+	out := make(rotation.KeyList, len(s.keys))
+	for i, k := range s.keys {
+		out[i] = k.listed()
+	}
+	return out, nil
+}
+
+func (s *Store) MaximumKeys() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.capacity
+}
+
+//MarkUsed records the key with the given ID as used at the given time, as reported by Key.LastUsed.
+func (s *Store) MarkUsed(id string, at time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, k := range s.keys {
+		if k.ID == id {
+			k.lastUsed = at
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNoSuchKey, id)
+}
+
+//Calls is the number of times the operation has been invoked, including invocations which failed.
+func (s *Store) Calls(operation rotation.OperationKind) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[operation]
+}
+
+//begin waits out any latency then locks the store, counting the invocation of the operation.  Returns with the store
+//locked unless an error is returned.
+func (s *Store) begin(ctx context.Context, operation rotation.OperationKind) error {
+	s.lock.Lock()
+	s.calls[operation]++
+	call := s.calls[operation]
+	latency := s.latency
+	s.lock.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	if err, ok := s.failures[operation][call]; ok {
+		delete(s.failures[operation], call)
+		s.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+//find locates the index of the key with the same ID, failing with ErrNoSuchKey if not within the store.
+func (s *Store) find(operation rotation.OperationKind, key rotation.Key) (int, error) {
+	if id, ok := rotation.IDOf(key); ok {
+		for i, k := range s.keys {
+			if k.ID == id {
+				return i, nil
+			}
+		}
+	}
+	return 0, s.fail(operation, nil, fmt.Errorf("%w: %+v", ErrNoSuchKey, key))
+}
+
+//fail produces a StoreError for the operation.
+func (s *Store) fail(operation rotation.OperationKind, kind error, err error) error {
+	return &rotation.StoreError{Operation: operation, Target: s.target, Kind: kind, Err: err}
+}
+
+//generateSecret produces random secret material.
+func generateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,134 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"github.com/truewhitespace/key-rotation/rotation/rotationtest"
+	"testing"
+	"time"
+)
+
+func TestConformance(t *testing.T) {
+	for _, capacity := range []int{1, DefaultCapacity, 5} {
+		t.Run(fmt.Sprintf("Capacity%d", capacity), func(t *testing.T) {
+			rotationtest.Run(t, func(t *testing.T, clock rotation.Clock) rotation.KeyStore {
+				return New(WithCapacity(capacity), WithClock(clock))
+			})
+		})
+	}
+}
+
+func TestSecretOnlyRevealedOnCreation(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	key, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if secret, ok := rotation.SecretOf(key); !ok || len(secret) != 40 {
+		t.Errorf("expected a generated secret, got %q", secret)
+	}
+
+	listed, err := store.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if secret, ok := rotation.SecretOf(listed[0]); ok {
+		t.Errorf("expected listed key not to reveal the secret, got %q", secret)
+	}
+}
+
+func TestFailNth(t *testing.T) {
+	ctx := context.Background()
+	store := New(WithCapacity(5))
+	injected := errors.New("injected")
+	store.FailNth(rotation.OperationCreate, 2, injected)
+
+	if _, err := store.CreateKey(ctx); err != nil {
+		t.Fatalf("expected first creation to succeed, got %s", err)
+	}
+	if _, err := store.CreateKey(ctx); !errors.Is(err, injected) {
+		t.Fatalf("expected second creation to fail, got %v", err)
+	}
+	if _, err := store.CreateKey(ctx); err != nil {
+		t.Fatalf("expected third creation to succeed, got %s", err)
+	}
+
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("expected the failed creation to make no key, got %d keys", len(keys))
+	}
+	if calls := store.Calls(rotation.OperationCreate); calls != 3 {
+		t.Errorf("expected 3 creations, got %d", calls)
+	}
+}
+
+func TestThrottleIsRetried(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	store.Throttle(rotation.OperationCreate, 2)
+
+	retrying := rotation.NewRetryingKeyStore(store, func(err error) bool {
+		return errors.Is(err, rotation.ErrThrottled)
+	}, rotation.WithBackoff(rotation.Backoff{Initial: time.Millisecond, Maximum: time.Millisecond}))
+	if _, err := retrying.CreateKey(ctx); err != nil {
+		t.Fatalf("expected creation to succeed once no longer throttled, got %s", err)
+	}
+	if calls := store.Calls(rotation.OperationCreate); calls != 3 {
+		t.Errorf("expected 3 creations, got %d", calls)
+	}
+
+	store.Throttle(rotation.OperationList, 1)
+	var storeErr *rotation.StoreError
+	if _, err := store.ListKeys(ctx); !errors.As(err, &storeErr) || !errors.Is(err, rotation.ErrThrottled) {
+		t.Errorf("expected a throttled store error, got %v", err)
+	}
+}
+
+func TestLatencyHonorsContext(t *testing.T) {
+	store := New(WithLatency(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := store.CreateKey(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected creation to be abandoned, got %v", err)
+	}
+	store.SetLatency(0)
+	keys, err := store.ListKeys(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected no keys, got %d", len(keys))
+	}
+}
+
+func TestMarkUsed(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	key, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	used := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	id, _ := rotation.IDOf(key)
+	if err := store.MarkUsed(id, used); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if err := store.MarkUsed("missing", used); !errors.Is(err, ErrNoSuchKey) {
+		t.Errorf("expected unknown key to fail, got %v", err)
+	}
+
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if at, ok := rotation.LastUsedOf(keys[0]); !ok || !at.Equal(used) {
+		t.Errorf("expected key last used at %s, got %s", used, at)
+	}
+}
@@ -1,6 +1,7 @@
 package rotation
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -89,6 +90,26 @@ func TestDestroysTargetKeys(t *testing.T) {
 	store.assertKeyCountDeleted(t, 1)
 }
 
+func TestApplyEnforcesDestructiveLimit(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	plan := KeyRotationPlan{
+		DestroyKeys:                  KeyList{&mockKey{created: InvalidTime()}, &mockKey{created: InvalidTime()}},
+		MaximumDestructiveOperations: 1,
+	}
+	if _, err := plan.Apply(ctx, store); err == nil {
+		t.Error("Expected a plan exceeding the destructive limit of its policy to fail")
+	}
+	store.assertKeyCountDeleted(t, 0)
+
+	plan.MaximumDestructiveOperations = 2
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+	store.assertKeyCountDeleted(t, 2)
+}
+
 func TestCreateAppendsKey(t *testing.T) {
 	ctx, done := testContext(t)
 	defer done()
@@ -96,8 +117,8 @@ func TestCreateAppendsKey(t *testing.T) {
 	store := newMock()
 	existingKey := &mockKey{created: InvalidTime()}
 	plan := KeyRotationPlan{
-		CreateKey: true,
-		goodKeys:  KeyList{existingKey},
+		CreateKey:      true,
+		Classification: Classification{{Key: existingKey, State: KeyValid}},
 	}
 	keys, err := plan.Apply(ctx, store)
 	assertNoError(t, err)
@@ -106,3 +127,153 @@ func TestCreateAppendsKey(t *testing.T) {
 	store.assertCreatedKey(t)
 	store.assertKeyCountDeleted(t, 0)
 }
+
+func (m *mockKeyStore) assertEvents(t *testing.T, expected ...string) {
+	t.Helper()
+	if len(m.events) != len(expected) {
+		t.Fatalf("Expected events %q, got %q", expected, m.events)
+	}
+	for i, e := range expected {
+		if m.events[i] != e {
+			t.Errorf("Expected event %d to be %q, got %q", i, e, m.events[i])
+		}
+	}
+}
+
+func TestCreatesBeforeDestroyingWithAvailableSlot(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	expired := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{expired},
+	}
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+
+	store.assertEvents(t, "create mock-2", "delete mock-1")
+}
+
+func TestDestroysOnlyEnoughToFreeSlotBeforeCreate(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	expired := store.mockExpired(5)
+	grace := store.mockInGrace()
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{expired, grace},
+		Classification: Classification{
+			{Key: expired, State: KeyExpired},
+			{Key: grace, State: KeyGrace},
+		},
+	}
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+
+	store.assertEvents(t, "delete mock-1", "create mock-3", "delete mock-2")
+}
+
+func TestFailedCreateWithAvailableSlotDestroysNothing(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.createErr = errors.New("throttled")
+	expired := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{expired},
+	}
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("Expected an ApplyError, got %v", err)
+	}
+	assertEmptyKeyList(t, applyErr.Deleted)
+	store.assertKeyCountDeleted(t, 0)
+	if !applyErr.Remaining.CreateKey {
+		t.Error("Expected creation to remain")
+	}
+	assertKeyListSize(t, applyErr.Remaining.DestroyKeys, 1)
+}
+
+func TestFailedCreateAtCapacityReportsDeletedKeys(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	store.createErr = errors.New("throttled")
+	first := store.mockInGrace()
+	second := store.mockInGrace()
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{first},
+		Classification: Classification{
+			{Key: first, State: KeyGrace},
+			{Key: second, State: KeyGrace},
+		},
+	}
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("Expected an ApplyError, got %v", err)
+	}
+	if !errors.Is(err, store.createErr) {
+		t.Errorf("Expected cause to be preserved, got %v", err)
+	}
+	assertKeyListSize(t, applyErr.Deleted, 1)
+	if applyErr.Created != nil {
+		t.Errorf("Expected no key to be created, got %+v", applyErr.Created)
+	}
+	remaining := applyErr.Remaining
+	if !remaining.CreateKey {
+		t.Error("Expected creation to remain")
+	}
+	assertEmptyKeyList(t, remaining.DestroyKeys)
+	assertKeyListSize(t, remaining.Classification.Keys(KeyGrace), 1)
+}
+
+func TestDisablesAfterCreating(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	key := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		CreateKey:      true,
+		DisableKeys:    KeyList{key},
+		Classification: Classification{{Key: key, State: KeyDisabled}},
+	}
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+
+	store.assertEvents(t, "create mock-2", "disable mock-1")
+	if key.Active() {
+		t.Error("Expected key to be disabled")
+	}
+}
+
+func TestDisableRequiresDisablingStore(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DisableKeys: KeyList{store.mockExpired(5)},
+	}
+	if _, err := plan.Apply(ctx, &KeyStoreDecorator{Wrapped: store}); err == nil {
+		t.Error("Expected apply to fail for a store unable to disable keys")
+	}
+	store.assertNoKeysCreated(t)
+}
@@ -0,0 +1,206 @@
+package rotation
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+//AuditOutcome records whether an audited operation succeeded.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+//AuditEntry is a single line of an audit log.  Each entry includes the hash of the entry before it, so removing or
+//altering any entry breaks the chain.
+type AuditEntry struct {
+	//Sequence counts entries from one within the log.
+	Sequence  uint64        `json:"sequence"`
+	Time      time.Time     `json:"time"`
+	Target    string        `json:"target,omitempty"`
+	Operation OperationKind `json:"operation"`
+	KeyID     string        `json:"key_id,omitempty"`
+	Outcome   AuditOutcome  `json:"outcome"`
+	Error     string        `json:"error,omitempty"`
+	Operator  string        `json:"operator,omitempty"`
+	Policy    string        `json:"policy,omitempty"`
+	//Previous is the hash of the prior entry, empty for the first entry.
+	Previous string `json:"previous"`
+	//Hash is the SHA-256 of the entry encoded with an empty Hash.
+	Hash string `json:"hash"`
+}
+
+//computeHash produces the hash of the entry over every field other than Hash.
+func (a AuditEntry) computeHash() (string, error) {
+	a.Hash = ""
+	data, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//AuditHead identifies the last entry of an audit log, allowing a log to be resumed or checked against a previously
+//recorded head to detect truncation.
+type AuditHead struct {
+	Sequence uint64
+	Hash     string
+}
+
+//AuditLog appends hash chained JSON Lines entries to a sink.  Safe for concurrent use.
+type AuditLog struct {
+	lock  sync.Mutex
+	out   io.Writer
+	head  AuditHead
+	clock Clock
+}
+
+//NewAuditLog writes entries to out, continuing the chain from head.  The zero AuditHead starts a new log.  Entries are
+//timestamped using the clock, or now when nil.
+func NewAuditLog(out io.Writer, head AuditHead, clock Clock) *AuditLog {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &AuditLog{out: out, head: head, clock: clock}
+}
+
+//Head is the last entry written.
+func (a *AuditLog) Head() AuditHead {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.head
+}
+
+//Record chains the entry to the log, filling in the sequence, time, and hashes.
+func (a *AuditLog) Record(entry AuditEntry) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	entry.Sequence = a.head.Sequence + 1
+	entry.Time = a.clock.Now().UTC()
+	entry.Previous = a.head.Hash
+	hash, err := entry.computeHash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := a.out.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	a.head = AuditHead{Sequence: entry.Sequence, Hash: entry.Hash}
+	return nil
+}
+
+//VerifyAuditLog checks every entry of a log, producing the head of the log when intact.  Modified, reordered, or
+//removed entries are reported along with their line.  Removal of entries from the end of a log can only be detected
+//by comparing the result against a head recorded elsewhere.
+func VerifyAuditLog(in io.Reader) (AuditHead, error) {
+	head := AuditHead{}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return head, fmt.Errorf("line %d: malformed entry: %w", line, err)
+		}
+		if entry.Sequence != head.Sequence+1 {
+			return head, fmt.Errorf("line %d: expected sequence %d, got %d", line, head.Sequence+1, entry.Sequence)
+		}
+		if entry.Previous != head.Hash {
+			return head, fmt.Errorf("line %d: chain broken, previous hash does not match entry %d", line, head.Sequence)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return head, err
+		}
+		if hash != entry.Hash {
+			return head, fmt.Errorf("line %d: entry %d has been modified", line, entry.Sequence)
+		}
+		head = AuditHead{Sequence: entry.Sequence, Hash: entry.Hash}
+	}
+	return head, scanner.Err()
+}
+
+//AuditScope describes who is changing which store and why, recorded with each entry.
+type AuditScope struct {
+	Target   string
+	Operator string
+	Policy   string
+}
+
+//AuditingKeyStore records each change made to the wrapped store within an AuditLog.  Secrets are never recorded.  An
+//operation is reported as failed if its entry can not be recorded, even when the store performed the operation.  Keys
+//created without being recorded are still returned.
+type AuditingKeyStore struct {
+	KeyStoreDecorator
+	log   *AuditLog
+	scope AuditScope
+}
+
+//NewAuditingKeyStore wraps the store, recording each change to its keys within the log.
+func NewAuditingKeyStore(store KeyStore, log *AuditLog, scope AuditScope) KeyStore {
+	auditing := &AuditingKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		log:               log,
+		scope:             scope,
+	}
+	return PreserveCapabilities(auditing)
+}
+
+//record appends an entry for the operation, combining any failure to record with the error of the operation.
+func (a *AuditingKeyStore) record(kind OperationKind, key Key, opErr error) error {
+	entry := AuditEntry{
+		Target:    a.scope.Target,
+		Operation: kind,
+		Outcome:   AuditSuccess,
+		Operator:  a.scope.Operator,
+		Policy:    a.scope.Policy,
+	}
+	if key != nil {
+		entry.KeyID = describeKey(key)
+	}
+	if opErr != nil {
+		entry.Outcome = AuditFailure
+		entry.Error = opErr.Error()
+	}
+	if err := a.log.Record(entry); err != nil {
+		if opErr != nil {
+			return fmt.Errorf("%w (additionally failed recording within audit log: %s)", opErr, err.Error())
+		}
+		return fmt.Errorf("%s succeeded but recording within audit log failed: %w", kind, err)
+	}
+	return opErr
+}
+
+//CreateKey returns the created key along with the error when the key was created but could not be recorded, so the
+//key and its secret are not lost.
+func (a *AuditingKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	key, err := a.Wrapped.CreateKey(ctx)
+	return key, a.record(OperationCreate, key, err)
+}
+
+func (a *AuditingKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	return a.record(OperationDelete, key, a.Wrapped.DeleteKey(ctx, key))
+}
+
+func (a *AuditingKeyStore) DisableKey(ctx context.Context, key Key) error {
+	return a.record(OperationDisable, key, disableWith(ctx, a.Wrapped, key))
+}
+
+func (a *AuditingKeyStore) EnableKey(ctx context.Context, key Key) error {
+	return a.record(OperationEnable, key, enableWith(ctx, a.Wrapped, key))
+}
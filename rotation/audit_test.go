@@ -0,0 +1,128 @@
+package rotation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func auditedRotation(t *testing.T) (*bytes.Buffer, *AuditLog) {
+	t.Helper()
+	ctx, done := testContext(t)
+	defer done()
+
+	var out bytes.Buffer
+	log := NewAuditLog(&out, AuditHead{}, FixedClock(mockNow))
+	mock := newMock()
+	mock.mockExpired(5)
+	store := NewAuditingKeyStore(mock, log, AuditScope{Target: "alice", Operator: "ops", Policy: "graceful-expiration"})
+
+	plan := planFor(t, store)
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+	return &out, log
+}
+
+func TestAuditRecordsOperations(t *testing.T) {
+	out, log := auditedRotation(t)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 entries, got %q", lines)
+	}
+	var created, deleted AuditEntry
+	assertNoError(t, json.Unmarshal([]byte(lines[0]), &created))
+	assertNoError(t, json.Unmarshal([]byte(lines[1]), &deleted))
+	if created.Operation != OperationCreate || created.KeyID != "mock-2" || created.Outcome != AuditSuccess {
+		t.Errorf("Expected successful creation of mock-2, got %+v", created)
+	}
+	if deleted.Operation != OperationDelete || deleted.KeyID != "mock-1" || deleted.Previous != created.Hash {
+		t.Errorf("Expected deletion of mock-1 chained to the creation, got %+v", deleted)
+	}
+	if created.Target != "alice" || created.Operator != "ops" || created.Policy != "graceful-expiration" {
+		t.Errorf("Expected scope to be recorded, got %+v", created)
+	}
+
+	head, err := VerifyAuditLog(strings.NewReader(out.String()))
+	assertNoError(t, err)
+	if head != log.Head() {
+		t.Errorf("Expected head %+v, got %+v", log.Head(), head)
+	}
+}
+
+func TestAuditRecordsFailures(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	var out bytes.Buffer
+	mock := newMock()
+	mock.createErr = errors.New("throttled")
+	store := NewAuditingKeyStore(mock, NewAuditLog(&out, AuditHead{}, nil), AuditScope{})
+	if _, err := store.CreateKey(ctx); !errors.Is(err, mock.createErr) {
+		t.Fatalf("Expected the store error, got %v", err)
+	}
+
+	var entry AuditEntry
+	assertNoError(t, json.Unmarshal(out.Bytes(), &entry))
+	if entry.Outcome != AuditFailure || entry.Error != "throttled" {
+		t.Errorf("Expected a failure to be recorded, got %+v", entry)
+	}
+}
+
+//failingWriter refuses every write.
+type failingWriter struct{}
+
+func (f failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAuditReturnsUnrecordedKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	store := NewAuditingKeyStore(mock, NewAuditLog(failingWriter{}, AuditHead{}, nil), AuditScope{})
+	plan := planFor(t, store)
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("Expected apply to fail recording the key, got %v", err)
+	}
+	mock.assertCreatedKey(t)
+	if applyErr.Created == nil || applyErr.Remaining.CreateKey {
+		t.Errorf("Expected the unrecorded key to be reported as created, got %+v", applyErr)
+	}
+}
+
+func TestAuditVerifyDetectsTampering(t *testing.T) {
+	out, _ := auditedRotation(t)
+	lines := strings.SplitAfter(out.String(), "\n")
+
+	modified := strings.Replace(out.String(), "mock-1", "mock-9", 1)
+	if _, err := VerifyAuditLog(strings.NewReader(modified)); err == nil {
+		t.Error("Expected a modified entry to be detected")
+	}
+	if _, err := VerifyAuditLog(strings.NewReader(lines[1])); err == nil {
+		t.Error("Expected a removed entry to be detected")
+	}
+	truncated, err := VerifyAuditLog(strings.NewReader(lines[0]))
+	assertNoError(t, err)
+	if truncated.Sequence != 1 {
+		t.Errorf("Expected the truncated head to be reported, got %+v", truncated)
+	}
+}
+
+func TestAuditResumesChain(t *testing.T) {
+	out, log := auditedRotation(t)
+	resumed := NewAuditLog(out, log.Head(), nil)
+	assertNoError(t, resumed.Record(AuditEntry{Operation: OperationCreate}))
+
+	head, err := VerifyAuditLog(strings.NewReader(out.String()))
+	assertNoError(t, err)
+	if head.Sequence != 3 {
+		t.Errorf("Expected 3 entries, got %+v", head)
+	}
+}
@@ -0,0 +1,26 @@
+package rotation
+
+import "time"
+
+//Clock provides the current time to rotation policies.  Substituting a clock allows for deterministic tests and
+//previewing plans as they would be at another point in time.
+type Clock interface {
+	Now() time.Time
+}
+
+//ClockFunc adapts a function into a Clock.
+type ClockFunc func() time.Time
+
+func (c ClockFunc) Now() time.Time {
+	return c()
+}
+
+//SystemClock reports the current time of the local system.
+var SystemClock Clock = ClockFunc(time.Now)
+
+//FixedClock produces a Clock which always reports the given instant.
+func FixedClock(at time.Time) Clock {
+	return ClockFunc(func() time.Time {
+		return at
+	})
+}
@@ -0,0 +1,151 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+)
+
+//WrappingKeyStore is implemented by decorators exposing the store they wrap, such as those extending
+//KeyStoreDecorator.
+type WrappingKeyStore interface {
+	KeyStore
+	Unwrap() KeyStore
+}
+
+//Unwrap produces the store wrapped by a decorator, nil if the store does not wrap another.
+func Unwrap(store KeyStore) KeyStore {
+	if wrapping, ok := store.(WrappingKeyStore); ok {
+		return wrapping.Unwrap()
+	}
+	return nil
+}
+
+//Find walks a chain of decorators from the outermost store inwards, producing the first store implementing T.  Used
+//to discover capabilities not forwarded by decorators, such as the operations recorded by a DryRunKeyStore.
+//Operations changing keys should be invoked upon the outermost store rather than the store found, otherwise the
+//decorators in between are bypassed.
+func Find[T any](store KeyStore) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+		store = Unwrap(store)
+	}
+	var none T
+	return none, false
+}
+
+//PreserveCapabilities exposes exactly the optional capabilities of the store wrapped by a decorator, so stacking
+//decorators never hides the ability to disable or enable keys nor claims it for stores lacking it.  Operations the
+//decorator implements are passed through it, otherwise they are forwarded directly to the wrapped store.  The decorator
+//is returned unchanged when it already matches the wrapped store.
+func PreserveCapabilities(decorator WrappingKeyStore) KeyStore {
+	wrapped := decorator.Unwrap()
+	_, disabling := decorator.(DisablingKeyStore)
+	_, enabling := decorator.(EnablingKeyStore)
+	_, canDisable := wrapped.(DisablingKeyStore)
+	_, canEnable := wrapped.(EnablingKeyStore)
+	if disabling == canDisable && enabling == canEnable {
+		return decorator
+	}
+
+	preserving := &preservingKeyStore{decorator: decorator}
+	switch {
+	case canDisable && canEnable:
+		return &togglingPreservingKeyStore{preserving}
+	case canDisable:
+		return &disablingPreservingKeyStore{preserving}
+	case canEnable:
+		return &enablingPreservingKeyStore{preserving}
+	default:
+		return preserving
+	}
+}
+
+//disableWith disables the key using the store, failing if the store is unable to disable keys.
+func disableWith(ctx context.Context, store KeyStore, key Key) error {
+	disabling, ok := store.(DisablingKeyStore)
+	if !ok {
+		return errors.New("store does not support disabling keys")
+	}
+	return disabling.DisableKey(ctx, key)
+}
+
+//enableWith enables the key using the store, failing if the store is unable to enable keys.
+func enableWith(ctx context.Context, store KeyStore, key Key) error {
+	enabling, ok := store.(EnablingKeyStore)
+	if !ok {
+		return errors.New("store does not support enabling keys")
+	}
+	return enabling.EnableKey(ctx, key)
+}
+
+//preservingKeyStore delegates to a decorator while remaining part of its chain.
+type preservingKeyStore struct {
+	decorator WrappingKeyStore
+}
+
+func (p *preservingKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	return p.decorator.CreateKey(ctx)
+}
+
+func (p *preservingKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	return p.decorator.DeleteKey(ctx, key)
+}
+
+func (p *preservingKeyStore) ListKeys(ctx context.Context) (KeyList, error) {
+	return p.decorator.ListKeys(ctx)
+}
+
+func (p *preservingKeyStore) MaximumKeys() int {
+	return p.decorator.MaximumKeys()
+}
+
+//Unwrap produces the decorator so Find continues to discover it.
+func (p *preservingKeyStore) Unwrap() KeyStore {
+	return p.decorator
+}
+
+//disableKey uses the decorator's implementation when present, otherwise the wrapped store's.
+func (p *preservingKeyStore) disableKey(ctx context.Context, key Key) error {
+	if _, ok := p.decorator.(DisablingKeyStore); ok {
+		return disableWith(ctx, p.decorator, key)
+	}
+	return disableWith(ctx, p.decorator.Unwrap(), key)
+}
+
+//enableKey uses the decorator's implementation when present, otherwise the wrapped store's.
+func (p *preservingKeyStore) enableKey(ctx context.Context, key Key) error {
+	if _, ok := p.decorator.(EnablingKeyStore); ok {
+		return enableWith(ctx, p.decorator, key)
+	}
+	return enableWith(ctx, p.decorator.Unwrap(), key)
+}
+
+type disablingPreservingKeyStore struct {
+	*preservingKeyStore
+}
+
+func (d *disablingPreservingKeyStore) DisableKey(ctx context.Context, key Key) error {
+	return d.disableKey(ctx, key)
+}
+
+type enablingPreservingKeyStore struct {
+	*preservingKeyStore
+}
+
+func (e *enablingPreservingKeyStore) EnableKey(ctx context.Context, key Key) error {
+	return e.enableKey(ctx, key)
+}
+
+type togglingPreservingKeyStore struct {
+	*preservingKeyStore
+}
+
+func (t *togglingPreservingKeyStore) DisableKey(ctx context.Context, key Key) error {
+	return t.disableKey(ctx, key)
+}
+
+func (t *togglingPreservingKeyStore) EnableKey(ctx context.Context, key Key) error {
+	return t.enableKey(ctx, key)
+}
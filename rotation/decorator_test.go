@@ -0,0 +1,86 @@
+package rotation
+
+import (
+	"context"
+	"testing"
+)
+
+//countingDecorator overrides only DisableKey, relying upon PreserveCapabilities for EnableKey.
+type countingDecorator struct {
+	KeyStoreDecorator
+	disabled int
+}
+
+func (c *countingDecorator) DisableKey(ctx context.Context, key Key) error {
+	c.disabled++
+	return disableWith(ctx, c.Wrapped, key)
+}
+
+func TestFindThroughDecorators(t *testing.T) {
+	dryRun := NewDryRunKeyStore(newMock(), nil)
+	store := NewRetryingKeyStore(NewTracingKeyStore(dryRun, "target"), isTransient)
+
+	if found, ok := Find[*DryRunKeyStore](store); !ok || found != dryRun {
+		t.Errorf("Expected dry run store to be found, got %+v", found)
+	}
+	if _, ok := Find[*LockingKeyStore](store); ok {
+		t.Error("Expected no locking store to be found")
+	}
+}
+
+func TestPreserveCapabilitiesExposesWrapped(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	key := mock.mockGoodKey()
+	store := PreserveCapabilities(&KeyStoreDecorator{Wrapped: mock})
+
+	disabling, ok := store.(DisablingKeyStore)
+	if !ok {
+		t.Fatal("Expected decorator to disable keys of a disabling store")
+	}
+	if _, ok := store.(EnablingKeyStore); !ok {
+		t.Fatal("Expected decorator to enable keys of an enabling store")
+	}
+	assertNoError(t, disabling.DisableKey(ctx, key))
+	mock.assertEvents(t, "disable "+key.id)
+	if found, ok := Find[*mockKeyStore](store); !ok || found != mock {
+		t.Error("Expected wrapped store to be found")
+	}
+}
+
+func TestPreserveCapabilitiesUsesDecorator(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	key := mock.mockGoodKey()
+	counting := &countingDecorator{KeyStoreDecorator: KeyStoreDecorator{Wrapped: mock}}
+	store := PreserveCapabilities(counting)
+
+	assertNoError(t, store.(DisablingKeyStore).DisableKey(ctx, key))
+	assertNoError(t, store.(EnablingKeyStore).EnableKey(ctx, key))
+	if counting.disabled != 1 {
+		t.Errorf("Expected the decorator to disable the key, disabled %d", counting.disabled)
+	}
+	mock.assertEvents(t, "disable "+key.id, "enable "+key.id)
+	if found, ok := Find[*countingDecorator](store); !ok || found != counting {
+		t.Error("Expected decorator to be found")
+	}
+}
+
+func TestPreserveCapabilitiesHidesMissing(t *testing.T) {
+	plain := &KeyStoreDecorator{Wrapped: newMock()}
+	store := NewRetryingKeyStore(plain, isTransient)
+
+	if _, ok := store.(DisablingKeyStore); ok {
+		t.Error("Expected no ability to disable keys of a store unable to")
+	}
+	if _, ok := store.(EnablingKeyStore); ok {
+		t.Error("Expected no ability to enable keys of a store unable to")
+	}
+	if found, ok := Find[*KeyStoreDecorator](store); !ok || found != plain {
+		t.Errorf("Expected the plain store to be found, got %+v", found)
+	}
+}
@@ -0,0 +1,110 @@
+package rotation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//OperationKind is the type of operation performed against a store, such as a change a DryRunKeyStore intercepted.
+type OperationKind string
+
+const (
+	OperationCreate  OperationKind = "create"
+	OperationDelete  OperationKind = "delete"
+	OperationDisable OperationKind = "disable"
+	OperationEnable  OperationKind = "enable"
+	//OperationList reads the keys of a store and is never intercepted.
+	OperationList OperationKind = "list"
+)
+
+//Operation is a change which would have been made to a store.
+type Operation struct {
+	Kind OperationKind
+	//Key is the key operated upon.  For creation this is the synthetic key returned in place of a real key.
+	Key Key
+}
+
+//DryRunKeyStore lists keys from the wrapped store but records changes instead of performing them, allowing plans and
+//hooks to be exercised against real stores without side effects.  Keys are created as synthetic keys which only exist
+//within the recorded operations.
+type DryRunKeyStore struct {
+	KeyStoreDecorator
+	clock      Clock
+	lock       sync.Mutex
+	operations []Operation
+	created    int
+}
+
+//NewDryRunKeyStore wraps the store, recording changes instead of applying them.  Synthetic keys are created as of the
+//given clock, or now when nil.  Find locates the *DryRunKeyStore holding the recorded operations.
+func NewDryRunKeyStore(store KeyStore, clock Clock) KeyStore {
+	if clock == nil {
+		clock = SystemClock
+	}
+	dryRun := &DryRunKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		clock:             clock,
+	}
+	return PreserveCapabilities(dryRun)
+}
+
+//Operations are the changes which would have been made, in the order requested.
+func (d *DryRunKeyStore) Operations() []Operation {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]Operation{}, d.operations...)
+}
+
+func (d *DryRunKeyStore) record(kind OperationKind, key Key) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.operations = append(d.operations, Operation{Kind: kind, Key: key})
+}
+
+func (d *DryRunKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.created++
+	key := &syntheticKey{id: fmt.Sprintf("dry-run-%d", d.created), created: d.clock.Now()}
+	d.operations = append(d.operations, Operation{Kind: OperationCreate, Key: key})
+	return key, nil
+}
+
+func (d *DryRunKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	d.record(OperationDelete, key)
+	return nil
+}
+
+//DisableKey records the key as disabled.  Fails if the wrapped store is unable to disable keys.
+func (d *DryRunKeyStore) DisableKey(ctx context.Context, key Key) error {
+	if _, ok := d.Wrapped.(DisablingKeyStore); !ok {
+		return fmt.Errorf("store does not support disabling keys")
+	}
+	d.record(OperationDisable, key)
+	return nil
+}
+
+//EnableKey records the key as enabled.  Fails if the wrapped store is unable to enable keys.
+func (d *DryRunKeyStore) EnableKey(ctx context.Context, key Key) error {
+	if _, ok := d.Wrapped.(EnablingKeyStore); !ok {
+		return fmt.Errorf("store does not support enabling keys")
+	}
+	d.record(OperationEnable, key)
+	return nil
+}
+
+//syntheticKey stands in for a key which was never created.
+type syntheticKey struct {
+	id      string
+	created time.Time
+}
+
+func (s *syntheticKey) Created() time.Time {
+	return s.created
+}
+
+func (s *syntheticKey) KeyID() string {
+	return s.id
+}
@@ -0,0 +1,80 @@
+package rotation
+
+import (
+	"testing"
+)
+
+func TestDryRunRecordsOperationsWithoutChanges(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	store.mockExpired(5)
+	grace := store.mockInGrace()
+	dryRun := NewDryRunKeyStore(store, FixedClock(mockNow))
+
+	plan := planFor(t, dryRun)
+	keys, err := plan.Apply(ctx, dryRun)
+	assertNoError(t, err)
+	assertKeyListSize(t, keys, 1)
+
+	store.assertEvents(t)
+	listed, err := dryRun.ListKeys(ctx)
+	assertNoError(t, err)
+	assertKeyListSize(t, listed, 2)
+
+	recorder, ok := Find[*DryRunKeyStore](dryRun)
+	if !ok {
+		t.Fatal("Expected the dry run store to be found")
+	}
+	operations := recorder.Operations()
+	if len(operations) != 2 {
+		t.Fatalf("Expected 2 operations, got %+v", operations)
+	}
+	if operations[0].Kind != OperationDelete || operations[1].Kind != OperationCreate {
+		t.Errorf("Expected a delete then a create, got %+v", operations)
+	}
+	if operations[0].Key == grace {
+		t.Error("Expected the expired key to be deleted")
+	}
+	if id := operations[1].Key.(IdentifiableKey).KeyID(); id != "dry-run-1" {
+		t.Errorf("Expected a synthetic key, got %q", id)
+	}
+	if !operations[1].Key.Created().Equal(mockNow) {
+		t.Errorf("Expected synthetic key created at %s, got %s", mockNow, operations[1].Key.Created())
+	}
+}
+
+func TestDryRunRequiresDisablingStore(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	key := store.mockGoodKey()
+	dryRun := NewDryRunKeyStore(&KeyStoreDecorator{Wrapped: store}, nil)
+	if _, ok := dryRun.(DisablingKeyStore); ok {
+		t.Error("Expected the dry run store not to claim disabling for a store unable to disable keys")
+	}
+	if _, ok := dryRun.(EnablingKeyStore); ok {
+		t.Error("Expected the dry run store not to claim enabling for a store unable to enable keys")
+	}
+
+	recorder, _ := Find[*DryRunKeyStore](dryRun)
+	if err := recorder.DisableKey(ctx, key); err == nil {
+		t.Error("Expected disabling to fail for a store unable to disable keys")
+	}
+	if len(recorder.Operations()) != 0 {
+		t.Errorf("Expected no operations, got %+v", recorder.Operations())
+	}
+}
+
+func TestDryRunPreservesToggling(t *testing.T) {
+	dryRun := NewDryRunKeyStore(newMock(), nil)
+	if _, ok := dryRun.(DisablingKeyStore); !ok {
+		t.Error("Expected the dry run store to disable keys of a store able to")
+	}
+	if _, ok := dryRun.(EnablingKeyStore); !ok {
+		t.Error("Expected the dry run store to enable keys of a store able to")
+	}
+}
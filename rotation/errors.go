@@ -0,0 +1,95 @@
+package rotation
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Sentinel errors classifying failures, for use with errors.Is.  Bindings report failures of the underlying system
+//through StoreError so callers may react to them without knowledge of the binding.
+var (
+	//ErrNoCapacity indicates no slot is available for a new key.
+	ErrNoCapacity = errors.New("no capacity for a new key")
+	//ErrTargetNotFound indicates the target of a store, such as a user, does not exist.
+	ErrTargetNotFound = errors.New("target not found")
+	//ErrPermissionDenied indicates the credentials used by a store lack permission for the operation.
+	ErrPermissionDenied = errors.New("permission denied")
+	//ErrThrottled indicates the underlying system refused the operation due to rate limits.
+	ErrThrottled = errors.New("throttled")
+	//ErrStalePlan indicates the keys of a store have changed since a plan was produced.
+	ErrStalePlan = errors.New("plan is stale")
+)
+
+//CapacityError reports a plan or store unable to make room for a new key.
+type CapacityError struct {
+	//Maximum is the number of keys permitted by the store, zero if unknown.
+	Maximum int
+	Reason  string
+	//Err is the underlying cause, if any.
+	Err error
+}
+
+func (c *CapacityError) Error() string {
+	message := ErrNoCapacity.Error()
+	if c.Maximum > 0 {
+		message = fmt.Sprintf("%s, at maximum of %d keys", message, c.Maximum)
+	}
+	if c.Reason != "" {
+		message += ": " + c.Reason
+	}
+	if c.Err != nil {
+		message += ": " + c.Err.Error()
+	}
+	return message
+}
+
+func (c *CapacityError) Is(target error) bool {
+	return target == ErrNoCapacity
+}
+
+func (c *CapacityError) Unwrap() error {
+	return c.Err
+}
+
+//StalePlanError reports a persisted plan which no longer reflects the keys of the store.
+type StalePlanError struct {
+	//KeyID identifies the key which changed, empty when the set of keys differs.
+	KeyID  string
+	Reason string
+}
+
+func (s *StalePlanError) Error() string {
+	if s.KeyID == "" {
+		return fmt.Sprintf("%s: %s", ErrStalePlan, s.Reason)
+	}
+	return fmt.Sprintf("%s: key %q %s", ErrStalePlan, s.KeyID, s.Reason)
+}
+
+func (s *StalePlanError) Is(target error) bool {
+	return target == ErrStalePlan
+}
+
+//StoreError reports a failed operation against the underlying system of a binding.
+type StoreError struct {
+	Operation OperationKind
+	//Target names the target of the store, such as a user name.
+	Target string
+	//Kind is one of the sentinel errors classifying the failure, nil if unclassified.
+	Kind error
+	//Err is the error reported by the underlying system.
+	Err error
+}
+
+func (s *StoreError) Error() string {
+	if s.Kind == nil {
+		return fmt.Sprintf("%s failed for %s: %s", s.Operation, s.Target, s.Err)
+	}
+	return fmt.Sprintf("%s failed for %s: %s: %s", s.Operation, s.Target, s.Kind, s.Err)
+}
+
+func (s *StoreError) Unwrap() []error {
+	if s.Kind == nil {
+		return []error{s.Err}
+	}
+	return []error{s.Kind, s.Err}
+}
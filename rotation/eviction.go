@@ -0,0 +1,101 @@
+package rotation
+
+import (
+	"fmt"
+	"time"
+)
+
+//Eviction records the grace key selected for destruction to free a slot within a store at capacity.
+type Eviction struct {
+	Key Key
+	//Policy is the name of the EvictionPolicy which selected the key.
+	Policy string
+	//Reason is a human readable explanation of why the key was selected.
+	Reason string
+}
+
+//EvictionPolicy selects which grace key is destroyed when a store has reached the maximum number of keys and a new
+//key is required.
+type EvictionPolicy interface {
+	//Name identifies the policy within plans and the CLI.
+	Name() string
+	//Evict chooses one of the candidates for destruction along with the reason for the selection.  Candidates will
+	//contain at least one key.  An error refuses eviction, failing the plan.
+	Evict(candidates Classification) (Key, string, error)
+}
+
+var (
+	//EvictOldest destroys the grace key created the longest ago.
+	EvictOldest EvictionPolicy = &byCreationEviction{name: "oldest", newest: false}
+	//EvictNewest destroys the grace key created most recently.
+	EvictNewest EvictionPolicy = &byCreationEviction{name: "newest", newest: true}
+	//EvictLeastRecentlyUsed destroys the grace key which was used the longest ago according to the store.  Keys
+	//without usage data are considered never used and are evicted first, with ties going to the oldest key.
+	EvictLeastRecentlyUsed EvictionPolicy = &leastRecentlyUsedEviction{}
+	//RefuseEviction never destroys a grace key, failing the plan instead.
+	RefuseEviction EvictionPolicy = &refuseEviction{}
+)
+
+//EvictionPolicies are the built-in eviction policies.
+var EvictionPolicies = []EvictionPolicy{EvictOldest, EvictNewest, EvictLeastRecentlyUsed, RefuseEviction}
+
+//EvictionPolicyByName locates the built-in eviction policy with the given name.
+func EvictionPolicyByName(name string) (EvictionPolicy, error) {
+	for _, p := range EvictionPolicies {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no such eviction policy %q", name)
+}
+
+type byCreationEviction struct {
+	name   string
+	newest bool
+}
+
+func (b *byCreationEviction) Name() string {
+	return b.name
+}
+
+func (b *byCreationEviction) Evict(candidates Classification) (Key, string, error) {
+	sorted := candidates.SortByCreated()
+	if b.newest {
+		victim := sorted[len(sorted)-1]
+		return victim.Key, fmt.Sprintf("newest grace key, created %s", victim.Key.Created().Format(time.RFC3339)), nil
+	}
+	victim := sorted[0]
+	return victim.Key, fmt.Sprintf("oldest grace key, created %s", victim.Key.Created().Format(time.RFC3339)), nil
+}
+
+type leastRecentlyUsedEviction struct{}
+
+func (l *leastRecentlyUsedEviction) Name() string {
+	return "least-recently-used"
+}
+
+func (l *leastRecentlyUsedEviction) Evict(candidates Classification) (Key, string, error) {
+	var victim Key
+	var victimUsed time.Time
+	victimKnown := true
+	for _, c := range candidates.SortByCreated() {
+		used, known := LastUsedOf(c.Key)
+		if victim == nil || (victimKnown && (!known || used.Before(victimUsed))) {
+			victim, victimUsed, victimKnown = c.Key, used, known
+		}
+	}
+	if !victimKnown {
+		return victim, "least recently used grace key, never used", nil
+	}
+	return victim, fmt.Sprintf("least recently used grace key, last used %s", victimUsed.Format(time.RFC3339)), nil
+}
+
+type refuseEviction struct{}
+
+func (r *refuseEviction) Name() string {
+	return "refuse"
+}
+
+func (r *refuseEviction) Evict(candidates Classification) (Key, string, error) {
+	return nil, "", fmt.Errorf("refusing to evict one of %d grace keys to free a slot", len(candidates))
+}
@@ -0,0 +1,80 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func graceCandidates(keys ...*mockKey) Classification {
+	out := make(Classification, len(keys))
+	for i, k := range keys {
+		out[i] = KeyClassification{Key: k, State: KeyGrace}
+	}
+	return out
+}
+
+func assertEvicts(t *testing.T, policy EvictionPolicy, candidates Classification, expected Key) {
+	t.Helper()
+	victim, reason, err := policy.Evict(candidates)
+	if err != nil {
+		t.Fatalf("Expected %s to evict, got error %s", policy.Name(), err.Error())
+	}
+	if victim != expected {
+		t.Errorf("Expected %s to evict %+v, got %+v", policy.Name(), expected, victim)
+	}
+	if reason == "" {
+		t.Errorf("Expected %s to provide a reason", policy.Name())
+	}
+}
+
+func TestEvictOldest(t *testing.T) {
+	store := newMock()
+	newer := store.appendKeyExpiring(40)
+	older := store.appendKeyExpiring(50)
+	assertEvicts(t, EvictOldest, graceCandidates(newer, older), older)
+}
+
+func TestEvictNewest(t *testing.T) {
+	store := newMock()
+	older := store.appendKeyExpiring(50)
+	newer := store.appendKeyExpiring(40)
+	assertEvicts(t, EvictNewest, graceCandidates(older, newer), newer)
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	store := newMock()
+	older := store.appendKeyExpiring(50)
+	older.lastUsed = mockNow.Add(-1 * time.Second)
+	newer := store.appendKeyExpiring(40)
+	newer.lastUsed = mockNow.Add(-10 * time.Second)
+	assertEvicts(t, EvictLeastRecentlyUsed, graceCandidates(older, newer), newer)
+}
+
+func TestEvictLeastRecentlyUsedPrefersNeverUsed(t *testing.T) {
+	store := newMock()
+	used := store.appendKeyExpiring(50)
+	used.lastUsed = mockNow.Add(-10 * time.Second)
+	unused := store.appendKeyExpiring(40)
+	assertEvicts(t, EvictLeastRecentlyUsed, graceCandidates(used, unused), unused)
+}
+
+func TestRefuseEviction(t *testing.T) {
+	store := newMock()
+	key := store.mockInGrace()
+	if _, _, err := RefuseEviction.Evict(graceCandidates(key)); err == nil {
+		t.Error("Expected eviction to be refused")
+	}
+}
+
+func TestEvictionPolicyByName(t *testing.T) {
+	for _, p := range EvictionPolicies {
+		found, err := EvictionPolicyByName(p.Name())
+		assertNoError(t, err)
+		if found != p {
+			t.Errorf("Expected %s to be found by name", p.Name())
+		}
+	}
+	if _, err := EvictionPolicyByName("random"); err == nil {
+		t.Error("Expected unknown policy to be rejected")
+	}
+}
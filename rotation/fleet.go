@@ -0,0 +1,193 @@
+package rotation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//Target is a named KeyStore managed as part of a fleet.
+type Target struct {
+	//Name identifies the target within results, such as a user name.
+	Name  string
+	Store KeyStore
+}
+
+//Outcome summarizes the result of rotating a single target.
+type Outcome string
+
+const (
+	//OutcomeUnchanged targets required no changes.
+	OutcomeUnchanged Outcome = "unchanged"
+	//OutcomeRotated targets had keys created, disabled, or destroyed.
+	OutcomeRotated Outcome = "rotated"
+	//OutcomePlanned targets were only planned.
+	OutcomePlanned Outcome = "planned"
+	//OutcomeFailed targets encountered an error while planning or applying.
+	OutcomeFailed Outcome = "failed"
+)
+
+//TargetResult is the result of rotating a single target within a fleet.
+type TargetResult struct {
+	Target string
+	//Plan is the plan produced for the target.  nil if planning failed.
+	Plan *KeyRotationPlan
+	//Keys are the healthy keys after applying the plan.
+	Keys KeyList
+	//Created, Disabled, and Deleted are the changes actually made to the store, including those made before a failure.
+	//Keys retained by a hook veto are absent.
+	Created  Key
+	Disabled KeyList
+	Deleted  KeyList
+	Outcome  Outcome
+	Err      error
+	//Duration is how long the target took to plan and apply.
+	Duration time.Duration
+}
+
+//FleetResult aggregates the results of rotating each target, in the order the targets were provided.
+type FleetResult struct {
+	Results []TargetResult
+}
+
+//Failed lists the results of targets which failed.
+func (f *FleetResult) Failed() []TargetResult {
+	out := make([]TargetResult, 0)
+	for _, r := range f.Results {
+		if r.Outcome == OutcomeFailed {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+//Err summarizes the failures within the fleet, nil if all targets succeeded.
+func (f *FleetResult) Err() error {
+	failed := f.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) == 1 {
+		return fmt.Errorf("target %s failed: %w", failed[0].Target, failed[0].Err)
+	}
+	return fmt.Errorf("%d of %d targets failed, first was %s: %w", len(failed), len(f.Results), failed[0].Target, failed[0].Err)
+}
+
+//Executor plans and applies rotations across many targets concurrently.  A failure of one target does not prevent the
+//remaining targets from being rotated.  Targets whose stores are, or wrap, a Lockable store are locked from planning
+//until applied.
+type Executor struct {
+	Planner Planner
+	//Workers is the maximum number of targets processed at once.  Values less than one process a single target at a
+	//time.
+	Workers int
+	//Timeout bounds the time spent on each target.  Zero imposes no limit beyond the context provided to Run.
+	Timeout time.Duration
+	//PlanOnly produces plans without applying them.
+	PlanOnly bool
+	//Hooks are registered on each plan before it is applied, after any hooks registered by the Planner.
+	Hooks Hooks
+	//Metrics optionally records the keys of each target as of planning and the outcome of each rotation.
+	Metrics *Metrics
+}
+
+//Run rotates each target, returning once all targets have completed.
+func (e *Executor) Run(ctx context.Context, targets []Target) *FleetResult {
+	workers := e.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]TargetResult, len(targets))
+	pending := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(targets); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				results[i] = e.runTarget(ctx, targets[i])
+				if e.Metrics != nil {
+					e.Metrics.ObserveRun(results[i])
+				}
+			}
+		}()
+	}
+	for i := range targets {
+		pending <- i
+	}
+	close(pending)
+	wg.Wait()
+	return &FleetResult{Results: results}
+}
+
+//runTarget plans and applies a single target.
+func (e *Executor) runTarget(parent context.Context, target Target) (result TargetResult) {
+	parent, span := tracer().Start(withTarget(parent, target.Name), "Executor.rotate", attributeTarget.String(target.Name))
+	defer func() {
+		span.SetAttributes(attributeResult.String(string(result.Outcome)))
+		endSpan(span, result.Err)
+	}()
+
+	start := time.Now()
+	result = TargetResult{Target: target.Name}
+	failed := func(err error) TargetResult {
+		result.Outcome = OutcomeFailed
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	ctx := parent
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, e.Timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return failed(err)
+	}
+
+	//Hold the lock from planning until the plan has been applied so no other rotation acts on the same keys.
+	if lockable, ok := Find[Lockable](target.Store); ok && !e.PlanOnly {
+		if err := lockable.Lock(ctx); err != nil {
+			return failed(err)
+		}
+		defer func() {
+			if err := lockable.Unlock(context.WithoutCancel(ctx)); err != nil && result.Err == nil {
+				result = failed(fmt.Errorf("releasing lock: %w", err))
+			}
+		}()
+	}
+
+	plan, err := e.Planner.Plan(ctx, target.Store)
+	if err != nil {
+		return failed(err)
+	}
+	plan.Target = target.Name
+	result.Plan = plan
+	if e.Metrics != nil {
+		e.Metrics.ObservePlan(plan)
+	}
+	if e.PlanOnly {
+		result.Outcome = OutcomePlanned
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	plan.Hooks = append(plan.Hooks, e.Hooks...)
+	progress := &applyProgress{}
+	keys, err := plan.applyTraced(ctx, target.Store, progress)
+	result.Created, result.Disabled, result.Deleted = progress.created, progress.disabled, progress.deleted
+	if err != nil {
+		return failed(err)
+	}
+	result.Keys = keys
+	result.Outcome = OutcomeUnchanged
+	if result.Created != nil || len(result.Disabled) > 0 || len(result.Deleted) > 0 {
+		result.Outcome = OutcomeRotated
+	}
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,193 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+//concurrencyTracker records the peak number of stores listing keys at once.
+type concurrencyTracker struct {
+	lock    sync.Mutex
+	current int
+	peak    int
+}
+
+type trackedStore struct {
+	*mockKeyStore
+	tracker *concurrencyTracker
+}
+
+func (s *trackedStore) ListKeys(ctx context.Context) (KeyList, error) {
+	s.tracker.lock.Lock()
+	s.tracker.current++
+	if s.tracker.current > s.tracker.peak {
+		s.tracker.peak = s.tracker.current
+	}
+	s.tracker.lock.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	s.tracker.lock.Lock()
+	s.tracker.current--
+	s.tracker.lock.Unlock()
+	return s.mockKeyStore.ListKeys(ctx)
+}
+
+type hangingStore struct {
+	*mockKeyStore
+}
+
+func (h *hangingStore) ListKeys(ctx context.Context) (KeyList, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func testExecutor() *Executor {
+	return &Executor{
+		Planner: &GracefulExpiration{
+			maximumAge: 1 * time.Minute,
+			graceAge:   30 * time.Second,
+			clock:      FixedClock(mockNow),
+		},
+		Workers: 2,
+	}
+}
+
+func TestExecutorRotatesEachTarget(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	current := newMock()
+	current.mockGoodKey()
+	expired := newMock()
+	expired.mockExpired(5)
+
+	result := testExecutor().Run(ctx, []Target{
+		{Name: "current", Store: current},
+		{Name: "expired", Store: expired},
+	})
+	assertNoError(t, result.Err())
+
+	if result.Results[0].Target != "current" || result.Results[0].Outcome != OutcomeUnchanged {
+		t.Errorf("Expected current to be unchanged, got %+v", result.Results[0])
+	}
+	if result.Results[1].Target != "expired" || result.Results[1].Outcome != OutcomeRotated {
+		t.Errorf("Expected expired to be rotated, got %+v", result.Results[1])
+	}
+	if result.Results[1].Plan.Target != "expired" {
+		t.Errorf("Expected plan to name the target, got %q", result.Results[1].Plan.Target)
+	}
+	expired.assertCreatedKey(t)
+}
+
+func TestExecutorContinuesOnError(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	failing := newMock()
+	failing.createErr = errors.New("throttled")
+	healthy := newMock()
+
+	result := testExecutor().Run(ctx, []Target{
+		{Name: "failing", Store: failing},
+		{Name: "healthy", Store: healthy},
+	})
+
+	if len(result.Failed()) != 1 || result.Failed()[0].Target != "failing" {
+		t.Errorf("Expected only failing to fail, got %+v", result.Failed())
+	}
+	if !errors.Is(result.Err(), failing.createErr) {
+		t.Errorf("Expected aggregate error to wrap the cause, got %v", result.Err())
+	}
+	healthy.assertCreatedKey(t)
+}
+
+func TestExecutorBoundsConcurrency(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	tracker := &concurrencyTracker{}
+	targets := make([]Target, 8)
+	for i := range targets {
+		targets[i] = Target{Name: "target", Store: &trackedStore{mockKeyStore: newMock(), tracker: tracker}}
+	}
+
+	result := testExecutor().Run(ctx, targets)
+	assertNoError(t, result.Err())
+	if tracker.peak > 2 {
+		t.Errorf("Expected at most 2 concurrent targets, got %d", tracker.peak)
+	}
+}
+
+func TestExecutorTimesOutTargets(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	executor := testExecutor()
+	executor.Timeout = 10 * time.Millisecond
+	result := executor.Run(ctx, []Target{
+		{Name: "hanging", Store: &hangingStore{newMock()}},
+		{Name: "healthy", Store: newMock()},
+	})
+
+	if !errors.Is(result.Results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Expected hanging target to time out, got %v", result.Results[0].Err)
+	}
+	if result.Results[1].Outcome != OutcomeRotated {
+		t.Errorf("Expected healthy target to be rotated, got %+v", result.Results[1])
+	}
+}
+
+func TestExecutorPlanOnly(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	executor := testExecutor()
+	executor.PlanOnly = true
+	result := executor.Run(ctx, []Target{{Name: "planned", Store: store}})
+
+	if result.Results[0].Outcome != OutcomePlanned || result.Results[0].Plan == nil {
+		t.Errorf("Expected target to be planned, got %+v", result.Results[0])
+	}
+	store.assertNoKeysCreated(t)
+}
+
+func TestExecutorReportsChangesMade(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	vetoed := newMock()
+	vetoed.mockExpired(5)
+	failing := newMock()
+	failing.maximumCount = 1
+	failing.mockExpired(5)
+	failing.createErr = errors.New("throttled")
+
+	executor := testExecutor()
+	executor.Hooks = Hooks{&HookFuncs{OnBeforeDelete: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+		if plan.Target == "vetoed" {
+			return ErrVetoed
+		}
+		return nil
+	}}}
+	result := executor.Run(ctx, []Target{
+		{Name: "vetoed", Store: vetoed},
+		{Name: "failing", Store: failing},
+	})
+
+	retained := result.Results[0]
+	if retained.Outcome != OutcomeRotated || retained.Created == nil || len(retained.Deleted) != 0 {
+		t.Errorf("Expected only the creation to be reported, got %+v", retained)
+	}
+	if len(retained.Plan.DestroyKeys) != 1 {
+		t.Errorf("Expected the vetoed deletion to remain planned, got %+v", retained.Plan.DestroyKeys)
+	}
+	partial := result.Results[1]
+	if partial.Outcome != OutcomeFailed || partial.Created != nil || len(partial.Deleted) != 1 {
+		t.Errorf("Expected the deletion before the failure to be reported, got %+v", partial)
+	}
+}
@@ -4,73 +4,321 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 )
 
+//GracefulExpirationStrategy is the name GracefulExpiration is registered under.
+const GracefulExpirationStrategy = "graceful-expiration"
+
 //NewGracefulExpiration instantiates a new key rotation object given the maximum age and grace thresholds provided.
-func NewGracefulExpiration(maximumAge time.Duration, graceAge time.Duration) (*GracefulExpiration, error) {
+func NewGracefulExpiration(maximumAge time.Duration, graceAge time.Duration, options ...GracefulOption) (*GracefulExpiration, error) {
 	if maximumAge <= graceAge {
 		return nil, fmt.Errorf("maximum age (%d) must be greater than or equal to grace age (%d)", maximumAge, graceAge)
 	}
-	return &GracefulExpiration{
+	rotation := &GracefulExpiration{
 		maximumAge: maximumAge,
 		graceAge:   graceAge,
-	}, nil
+		clock:      SystemClock,
+		eviction:   EvictOldest,
+	}
+	for _, option := range options {
+		option(rotation)
+	}
+	if rotation.quarantine < 0 {
+		return nil, fmt.Errorf("quarantine (%s) must not be negative", rotation.quarantine)
+	}
+	if rotation.minimumInterval < 0 {
+		return nil, fmt.Errorf("minimum creation interval (%s) must not be negative", rotation.minimumInterval)
+	}
+	if rotation.maximumDestructive < 0 {
+		return nil, fmt.Errorf("maximum destructive operations (%d) must not be negative", rotation.maximumDestructive)
+	}
+	return rotation, nil
+}
+
+//GracefulOption configures optional behavior of a GracefulExpiration.
+type GracefulOption func(*GracefulExpiration)
+
+//WithClock sets the source of the current time used when planning.  Defaults to SystemClock.
+func WithClock(clock Clock) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.clock = clock
+	}
+}
+
+//WithEvictionPolicy sets how a grace key is selected for destruction when the store is at capacity.  Defaults to
+//EvictOldest.
+func WithEvictionPolicy(policy EvictionPolicy) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.eviction = policy
+	}
+}
+
+//WithQuarantine disables keys once they exceed the maximum age, destroying them only after the quarantine period has
+//also passed.  Requires a DisablingKeyStore.  Defaults to zero, destroying keys immediately.
+func WithQuarantine(quarantine time.Duration) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.quarantine = quarantine
+	}
+}
+
+//WithUsageGuard protects grace keys used within the window from being destroyed to free a slot.  Expired keys are
+//destroyed regardless of use.  Requires keys implementing UsageReportingKey; keys without usage data are unprotected.
+func WithUsageGuard(window time.Duration, guard UsageGuard) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.usageWindow = window
+		k.usageGuard = guard
+	}
+}
+
+//WithMinimumCreationInterval defers creating a new key until the newest existing key is at least the given age.  This
+//guards against misconfigured clocks or policies creating a key on every run and evicting keys still in use.
+func WithMinimumCreationInterval(interval time.Duration) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.minimumInterval = interval
+	}
+}
+
+//WithMaximumDestructiveOperations fails planning when the plan would disable or destroy more than the given number of
+//keys.  The limit is recorded within the plan and enforced again when applied.  Zero imposes no limit.
+func WithMaximumDestructiveOperations(limit int) GracefulOption {
+	return func(k *GracefulExpiration) {
+		k.maximumDestructive = limit
+	}
 }
 
 //GracefulExpiration is an algorithm for planning key rotation given a valid key period, and a grace period.  GracefulExpiration will
-//attempt to key one key in the active state at all times and destroy any keys exceeding the maximum duration.
+//attempt to key one key in the active state at all times and destroy any keys exceeding the maximum duration.  When a
+//quarantine is configured keys exceeding the maximum duration are first disabled and only destroyed once the
+//quarantine has also passed.
 //
-//If a KeyStore has reached a limit with all keys being in the grace period then one grace key will be selected at
-//random to be destroyed.
+//If a KeyStore has reached a limit with all keys being in the grace period then one grace key will be selected by the
+//configured EvictionPolicy to be destroyed.
 type GracefulExpiration struct {
 	maximumAge time.Duration
 	graceAge   time.Duration
+	clock      Clock
+	eviction   EvictionPolicy
+	quarantine time.Duration
+	//usageWindow protects recently used grace keys when positive.
+	usageWindow time.Duration
+	usageGuard  UsageGuard
+	//minimumInterval is the minimum age of the newest key before another is created.
+	minimumInterval time.Duration
+	//maximumDestructive caps the keys disabled or destroyed by a single plan when positive.
+	maximumDestructive int
 }
 
-func (k *GracefulExpiration) Plan(ctx context.Context, store KeyStore) (*KeyRotationPlan, error) {
-	graceStart := time.Now().Add(-1 * k.graceAge)
-	destroyBefore := time.Now().Add(-1 * k.maximumAge)
+//now is the current time according to the configured clock.
+func (k *GracefulExpiration) now() time.Time {
+	if k.clock == nil {
+		return SystemClock.Now()
+	}
+	return k.clock.Now()
+}
 
-	validKeys := make(KeyList, 0)
-	graceKeys := make(KeyList, 0)
-	expiredKeys := make(KeyList, 0)
+//evictionPolicy is the configured eviction policy.
+func (k *GracefulExpiration) evictionPolicy() EvictionPolicy {
+	if k.eviction == nil {
+		return EvictOldest
+	}
+	return k.eviction
+}
 
+//Policy describes the configuration of the rotation algorithm for recording within plans.
+func (k *GracefulExpiration) Policy() Policy {
+	policy := Policy{
+		Name: GracefulExpirationStrategy,
+		Parameters: map[string]string{
+			"maximum-age": k.maximumAge.String(),
+			"grace-age":   k.graceAge.String(),
+			"eviction":    k.evictionPolicy().Name(),
+			"quarantine":  k.quarantine.String(),
+		},
+	}
+	if k.minimumInterval > 0 {
+		policy.Parameters["minimum-creation-interval"] = k.minimumInterval.String()
+	}
+	if k.maximumDestructive > 0 {
+		policy.Parameters["maximum-destructive-operations"] = strconv.Itoa(k.maximumDestructive)
+	}
+	if k.usageWindow > 0 {
+		policy.Parameters["usage-window"] = k.usageWindow.String()
+		policy.Parameters["usage-guard"] = string(k.usageGuard)
+	}
+	return policy
+}
+
+//Classify determines the state of each key within the store without planning any changes.
+func (k *GracefulExpiration) Classify(ctx context.Context, store KeyStore) (Classification, error) {
 	keys, err := store.ListKeys(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return k.classify(k.now(), keys), nil
+}
+
+//ClassifyKeys determines the state of each key already listed from a store as of now.
+func (k *GracefulExpiration) ClassifyKeys(keys KeyList) Classification {
+	return k.classify(k.now(), keys)
+}
+
+//classify determines the state of each key relative to now.
+func (k *GracefulExpiration) classify(now time.Time, keys KeyList) Classification {
+	graceStart := now.Add(-1 * k.graceAge)
+	destroyBefore := now.Add(-1 * k.maximumAge)
+	quarantineEnd := destroyBefore.Add(-1 * k.quarantine)
 
-	for _, k := range keys {
-		created := k.Created()
-		if created.Before(destroyBefore) {
-			expiredKeys = append(expiredKeys, k)
+	out := make(Classification, len(keys))
+	for i, key := range keys {
+		created := key.Created()
+		entry := KeyClassification{
+			Key: key,
+			Age: now.Sub(created),
+		}
+		expires, expiring := ExpiresOf(key)
+		if created.Equal(InvalidTime()) {
+			entry.State = KeyExpired
+			entry.Reason = "key is invalid"
+		} else if expiring && !now.Before(expires) {
+			entry.State = KeyExpired
+			entry.Reason = fmt.Sprintf("expired by store at %s", expires.Format(time.RFC3339))
+		} else if !IsActive(key) && k.quarantine == 0 {
+			entry.State = KeyExpired
+			entry.Reason = "key is inactive"
+		} else if created.Before(quarantineEnd) {
+			entry.State = KeyExpired
+			if k.quarantine > 0 {
+				entry.Reason = fmt.Sprintf("older than maximum age of %s plus quarantine of %s", k.maximumAge, k.quarantine)
+			} else {
+				entry.Reason = fmt.Sprintf("older than maximum age of %s", k.maximumAge)
+			}
+		} else if created.Before(destroyBefore) {
+			entry.State = KeyDisabled
+			entry.Remaining = created.Sub(quarantineEnd)
+			entry.Reason = fmt.Sprintf("older than maximum age of %s, quarantined for %s", k.maximumAge, k.quarantine)
+		} else if !IsActive(key) {
+			entry.State = KeyDisabled
+			entry.Remaining = created.Sub(quarantineEnd)
+			entry.Reason = fmt.Sprintf("inactive, quarantined until %s past maximum age", k.quarantine)
 		} else if created.Before(graceStart) {
-			graceKeys = append(graceKeys, k)
+			entry.State = KeyGrace
+			entry.Remaining = created.Sub(destroyBefore)
+			entry.Reason = fmt.Sprintf("older than grace age of %s", k.graceAge)
 		} else {
-			validKeys = append(validKeys, k)
+			entry.State = KeyValid
+			entry.Remaining = created.Sub(graceStart)
+			entry.Reason = fmt.Sprintf("younger than grace age of %s", k.graceAge)
 		}
+		out[i] = entry
 	}
+	return out
+}
+
+func (k *GracefulExpiration) Plan(ctx context.Context, store KeyStore) (plan *KeyRotationPlan, err error) {
+	ctx, span := tracer().Start(ctx, "GracefulExpiration.Plan", targetAttributes(ctx, store)...)
+	defer func() { endPlanSpan(span, plan, err) }()
+	return k.plan(ctx, store)
+}
 
-	graceKeyCount := len(graceKeys)
-	totalKeys := graceKeyCount + len(validKeys)
+//plan decides the operations required for the store as of now.
+func (k *GracefulExpiration) plan(ctx context.Context, store KeyStore) (*KeyRotationPlan, error) {
+	now := k.now()
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	classification := k.classify(now, keys)
+	states := classification.Partition()
+	validKeys := states[KeyValid]
+	graceKeys := states[KeyGrace]
+	disabledKeys := states[KeyDisabled]
+	expiredKeys := append(KeyList{}, states[KeyExpired]...)
+
+	disableKeys := disabledKeys.Filter(IsActive)
+	if len(disableKeys) > 0 {
+		if _, ok := store.(DisablingKeyStore); !ok {
+			return nil, errors.New("store does not support disabling keys for quarantine")
+		}
+	}
+
+	totalKeys := len(graceKeys) + len(validKeys) + len(disabledKeys)
 	willCreate := len(validKeys) == 0
+	var deferredUntil time.Time
+	if newest, ok := keys.Newest(); ok && willCreate && k.minimumInterval > 0 {
+		if until := newest.Created().Add(k.minimumInterval); now.Before(until) {
+			willCreate = false
+			deferredUntil = until
+		}
+	}
 	if willCreate {
 		totalKeys++
 	}
 
-	if totalKeys >= store.MaximumKeys() {
-		if graceKeyCount > 0 {
-			//destroy grace key at random
-			expiredKeys = append(expiredKeys, graceKeys[0])
+	var eviction *Eviction
+	var warnings []string
+	if totalKeys > store.MaximumKeys() {
+		if len(disabledKeys) > 0 {
+			//Keys already inactive are evicted before active keys awaiting quarantine, such as a key reactivated by
+			//Rollback, so the key clients are using is not destroyed outright.
+			victim, ok := disabledKeys.Filter(func(k Key) bool { return !IsActive(k) }).Oldest()
+			if !ok {
+				victim, _ = disabledKeys.Oldest()
+			}
+			expiredKeys = append(expiredKeys, victim)
+			disableKeys = disableKeys.Without(KeyList{victim})
+			eviction = &Eviction{Key: victim, Policy: "quarantine", Reason: "quarantine cut short to free a slot"}
+		} else if len(graceKeys) > 0 {
+			candidates := classification.InState(KeyGrace)
+			if k.usageGuard == UsageGuardRefuse {
+				candidates = k.unusedSince(now, candidates)
+				if len(candidates) == 0 {
+					return nil, &CapacityError{Maximum: store.MaximumKeys(), Reason: fmt.Sprintf("all %d grace keys were used within the last %s, refusing to destroy any", len(graceKeys), k.usageWindow)}
+				}
+			}
+			policy := k.evictionPolicy()
+			victim, reason, err := policy.Evict(candidates)
+			if err != nil {
+				return nil, &CapacityError{Maximum: store.MaximumKeys(), Err: err}
+			}
+			expiredKeys = append(expiredKeys, victim)
+			eviction = &Eviction{Key: victim, Policy: policy.Name(), Reason: reason}
+			if used, recent := usedWithin(victim, now, k.usageWindow); recent {
+				warnings = append(warnings, fmt.Sprintf("destroying grace key %s last used %s, within the usage window of %s", describeKey(victim), used.Format(time.RFC3339), k.usageWindow))
+			}
 		} else {
-			return nil, errors.New("no grace keys or available slots")
+			return nil, &CapacityError{Maximum: store.MaximumKeys(), Reason: "no grace keys to evict"}
 		}
 	}
 
+	if err := destructiveLimitError(len(disableKeys)+len(expiredKeys), k.maximumDestructive); err != nil {
+		return nil, err
+	}
+
 	return &KeyRotationPlan{
-		CreateKey:   willCreate,
-		DestroyKeys: expiredKeys,
-		goodKeys:    validKeys,
+		CreateKey:           willCreate,
+		CreateDeferredUntil: deferredUntil,
+		DisableKeys:         disableKeys,
+		DestroyKeys:         expiredKeys,
+		Policy:              k.Policy(),
+		Created:             now,
+		Classification:      classification,
+		Eviction:            eviction,
+		Warnings:            warnings,
+
+		MaximumDestructiveOperations: k.maximumDestructive,
 	}, nil
 }
+
+//unusedSince filters out keys used within the usage window.
+func (k *GracefulExpiration) unusedSince(now time.Time, candidates Classification) Classification {
+	out := make(Classification, 0, len(candidates))
+	for _, c := range candidates {
+		if _, recent := usedWithin(c.Key, now, k.usageWindow); !recent {
+			out = append(out, c)
+		}
+	}
+	return out
+}
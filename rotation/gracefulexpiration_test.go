@@ -2,6 +2,7 @@ package rotation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path"
 	"runtime"
@@ -52,9 +53,310 @@ func TestMaximumInGrace(t *testing.T) {
 		plan.assertCreating(t)
 		plan.assertDestroying(t, 1)
 		plan.assertNoGoodKeys(t)
+		assert(t, 1, plan.Eviction != nil && plan.Eviction.Key == plan.DestroyKeys[0], "Expected eviction to be recorded, got %+v", plan.Eviction)
 	})
 }
 
+func TestMaximumInGraceEvictsOldest(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	store.appendKeyExpiring(40)
+	oldest := store.appendKeyExpiring(50)
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	assertNoError(t, err)
+
+	plan.assertDestroying(t, 1)
+	if plan.DestroyKeys[0] != oldest {
+		t.Errorf("Expected oldest grace key to be evicted, got %+v", plan.DestroyKeys[0])
+	}
+	if plan.Eviction.Policy != "oldest" {
+		t.Errorf("Expected eviction policy to be recorded, got %q", plan.Eviction.Policy)
+	}
+}
+
+func TestMaximumInGraceRefusingEviction(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	store.mockInGrace()
+	store.mockInGrace()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithEvictionPolicy(RefuseEviction))
+	assertNoError(t, err)
+	if _, err := rotation.Plan(ctx, store); !errors.Is(err, ErrNoCapacity) {
+		t.Errorf("Expected plan to fail for lack of capacity when eviction is refused, got %v", err)
+	}
+}
+
+func TestClassifyReportsEachKeyState(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockGoodKey()
+	store.mockInGrace()
+	store.mockExpired(5)
+
+	rotation := &GracefulExpiration{
+		maximumAge: 1 * time.Minute,
+		graceAge:   30 * time.Second,
+		clock:      FixedClock(mockNow),
+	}
+	classification, err := rotation.Classify(ctx, store)
+	if err != nil {
+		t.Fatalf("Failed classifying because %s", err.Error())
+	}
+	if len(classification) != 3 {
+		t.Fatalf("Expected 3 classified keys, got %d", len(classification))
+	}
+
+	expected := []KeyState{KeyValid, KeyGrace, KeyExpired}
+	for i, c := range classification {
+		if c.State != expected[i] {
+			t.Errorf("Expected key %d to be %s, got %s", i, expected[i], c.State)
+		}
+		if c.Reason == "" {
+			t.Errorf("Expected key %d to have a reason", i)
+		}
+	}
+	if remaining := classification[0].Remaining; remaining != 30*time.Second {
+		t.Errorf("Expected valid key to enter grace in 30s, got %s", remaining)
+	}
+	if remaining := classification[1].Remaining; remaining != 15*time.Second {
+		t.Errorf("Expected grace key to expire in 15s, got %s", remaining)
+	}
+	if remaining := classification[2].Remaining; remaining != 0 {
+		t.Errorf("Expected expired key to have no remaining time, got %s", remaining)
+	}
+}
+
+func TestPlanAsOfFutureTime(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockGoodKey()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow.Add(2*time.Minute))))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	assertNoError(t, err)
+
+	plan.assertCreating(t)
+	plan.assertDestroying(t, 1)
+	if !plan.Created.Equal(mockNow.Add(2 * time.Minute)) {
+		t.Errorf("Expected plan to be created as of the clock, got %s", plan.Created)
+	}
+}
+
+func TestValidAndGraceAtCapacityKeepsGraceKey(t *testing.T) {
+	harnessRunPlan(t, func() KeyStore {
+		store := newMock()
+		store.maximumCount = 2
+		store.mockInGrace()
+		store.mockGoodKey()
+		return store
+	}, assertPlanNoOp)
+}
+
+func harnessQuarantinePlan(t *testing.T, store KeyStore) (*KeyRotationPlan, error) {
+	ctx, done := testContext(t)
+	defer done()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithQuarantine(30*time.Second))
+	assertNoError(t, err)
+	return rotation.Plan(ctx, store)
+}
+
+func TestQuarantineDisablesKeyPastMaximumAge(t *testing.T) {
+	store := newMock()
+	key := store.mockExpired(5)
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	plan.assertNotDestroying(t)
+	if len(plan.DisableKeys) != 1 || plan.DisableKeys[0] != key {
+		t.Errorf("Expected key to be disabled, got %+v", plan.DisableKeys)
+	}
+	if c, _ := plan.Classification.Find(key); c.State != KeyDisabled || c.Remaining != 25*time.Second {
+		t.Errorf("Expected key to be quarantined for another 25s, got %+v", c)
+	}
+}
+
+func TestQuarantineDestroysKeyAfterWindow(t *testing.T) {
+	store := newMock()
+	store.mockExpired(45)
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	plan.assertDestroying(t, 1)
+	assertEmptyKeyList(t, plan.DisableKeys)
+}
+
+func TestQuarantineRetainsInactiveKey(t *testing.T) {
+	store := newMock()
+	store.mockGoodKey()
+	store.mockInGrace().inactive = true
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	assertPlanNoOp(t, plan)
+	assertEmptyKeyList(t, plan.DisableKeys)
+	assertKeyListSize(t, plan.Classification.Keys(KeyDisabled), 1)
+}
+
+func TestQuarantinedKeyEvictedBeforeGraceKey(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	quarantined := store.mockExpired(5)
+	store.mockInGrace()
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != quarantined {
+		t.Errorf("Expected quarantined key to be destroyed, got %+v", plan.DestroyKeys)
+	}
+	assertEmptyKeyList(t, plan.DisableKeys)
+}
+
+func TestQuarantineEvictsInactiveKeyBeforeReactivatedKey(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	//Rolled back to: past the maximum age but reactivated.
+	reactivated := store.mockExpired(5)
+	deactivated := store.mockGoodKey()
+	deactivated.inactive = true
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != deactivated {
+		t.Errorf("Expected the deactivated key to be destroyed, got %+v", plan.DestroyKeys)
+	}
+	if len(plan.DisableKeys) != 1 || plan.DisableKeys[0] != reactivated {
+		t.Errorf("Expected the reactivated key to be quarantined, got %+v", plan.DisableKeys)
+	}
+}
+
+func TestQuarantinedKeyKeptWhileReplacementFits(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	quarantined := store.mockExpired(5)
+
+	plan, err := harnessQuarantinePlan(t, store)
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	plan.assertNotDestroying(t)
+	if len(plan.DisableKeys) != 1 || plan.DisableKeys[0] != quarantined {
+		t.Errorf("Expected quarantined key to be disabled rather than evicted, got %+v", plan.DisableKeys)
+	}
+	if plan.Eviction != nil {
+		t.Errorf("Expected no eviction while the replacement fits, got %+v", plan.Eviction)
+	}
+}
+
+func TestGraceKeyRetainedWhenReplacementFits(t *testing.T) {
+	harnessRunPlan(t, func() KeyStore {
+		store := newMock()
+		store.maximumCount = 2
+		store.mockInGrace()
+		return store
+	}, func(t *testing.T, plan *KeyRotationPlan) {
+		plan.assertCreating(t)
+		plan.assertNotDestroying(t)
+		assert(t, 1, plan.Eviction == nil, "Expected no eviction while the replacement fits, got %+v", plan.Eviction)
+	})
+}
+
+func TestQuarantineRequiresDisablingStore(t *testing.T) {
+	store := newMock()
+	store.mockExpired(5)
+
+	if _, err := harnessQuarantinePlan(t, &KeyStoreDecorator{Wrapped: store}); err == nil {
+		t.Error("Expected plan to fail for a store unable to disable keys")
+	}
+}
+
+func TestInactiveKeyWithoutQuarantineExpires(t *testing.T) {
+	harnessRunPlan(t, func() KeyStore {
+		store := newMock()
+		store.mockGoodKey().inactive = true
+		return store
+	}, func(t *testing.T, plan *KeyRotationPlan) {
+		plan.assertCreating(t)
+		plan.assertDestroying(t, 1)
+	})
+}
+
+func TestMinimumCreationIntervalDefersCreation(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockInGrace()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithMinimumCreationInterval(50*time.Second))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	assertNoError(t, err)
+
+	plan.assertNotCreating(t)
+	plan.assertNotDestroying(t)
+	if expected := mockNow.Add(5 * time.Second); !plan.CreateDeferredUntil.Equal(expected) {
+		t.Errorf("Expected creation deferred until %s, got %s", expected, plan.CreateDeferredUntil)
+	}
+}
+
+func TestMinimumCreationIntervalAllowsCreationOnceElapsed(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockInGrace()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithMinimumCreationInterval(40*time.Second))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	assertNoError(t, err)
+
+	plan.assertCreating(t)
+	if !plan.CreateDeferredUntil.IsZero() {
+		t.Errorf("Expected creation not to be deferred, got %s", plan.CreateDeferredUntil)
+	}
+}
+
+func TestMaximumDestructiveOperationsRefusesLargePlans(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockExpired(15)
+	store.mockExpired(5)
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithMaximumDestructiveOperations(1))
+	assertNoError(t, err)
+	if _, err := rotation.Plan(ctx, store); err == nil {
+		t.Error("Expected plan exceeding the destructive limit to fail")
+	}
+
+	rotation, err = NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithMaximumDestructiveOperations(2))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	assertNoError(t, err)
+	plan.assertDestroying(t, 2)
+}
+
 func testContext(t *testing.T) (context.Context, func()) {
 	//todo: include background
 	return context.WithCancel(context.Background())
@@ -81,12 +383,12 @@ func (plan *KeyRotationPlan) assertNotCreating(t *testing.T) {
 }
 
 func (plan *KeyRotationPlan) assertNoGoodKeys(t *testing.T) {
-	count := len(plan.goodKeys)
+	count := len(plan.Classification.Keys(KeyValid))
 	assert(t, 2, count == 0, "Expected 0 good keys, got %d", count)
 }
 
 func (plan *KeyRotationPlan) assertGoodKeysCount(t *testing.T, expected int) {
-	count := len(plan.goodKeys)
+	count := len(plan.Classification.Keys(KeyValid))
 	assert(t, 2, count != expected, "Expected %d good keys, got %d", expected, count)
 }
 
@@ -117,13 +419,13 @@ func assertPlanNoOp(t *testing.T, plan *KeyRotationPlan) {
 		t.Errorf("Expected to delete nothing, instead attempting to deleted %d", destroyCount)
 	}
 
-	goodKeyCount := len(plan.goodKeys)
+	goodKeyCount := len(plan.Classification.Keys(KeyValid))
 	if goodKeyCount == 0 {
 		t.Error("Expected good keys, got none")
 	}
-	for i, k := range plan.goodKeys {
+	for i, k := range plan.Classification.Keys(KeyValid) {
 		if k == nil {
-			t.Errorf("goodKeys[%d] is nil", i)
+			t.Errorf("valid key %d is nil", i)
 		}
 	}
 }
@@ -135,6 +437,7 @@ func harnessRunPlan(t *testing.T, given func() KeyStore, expect func(*testing.T,
 	rotation := &GracefulExpiration{
 		maximumAge: 1 * time.Minute,
 		graceAge:   30 * time.Second,
+		clock:      FixedClock(mockNow),
 	}
 
 	store := given()
@@ -145,3 +448,23 @@ func harnessRunPlan(t *testing.T, given func() KeyStore, expect func(*testing.T,
 	}
 	expect(t, plan)
 }
+
+func TestClassifyExpiresKeysExpiredByStore(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	valid := store.mockGoodKey()
+	store.keys[0] = &describedKey{mockKey: valid, expires: mockNow.Add(-time.Second)}
+
+	rotation := &GracefulExpiration{
+		maximumAge: 1 * time.Minute,
+		graceAge:   30 * time.Second,
+		clock:      FixedClock(mockNow),
+	}
+	classification, err := rotation.Classify(ctx, store)
+	assertNoError(t, err)
+	if classification[0].State != KeyExpired {
+		t.Errorf("Expected key past the expiry of its store to be expired, got %s", classification[0].State)
+	}
+}
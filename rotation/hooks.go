@@ -0,0 +1,98 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+)
+
+//ErrVetoed is returned, optionally wrapped, by Hook.BeforeDelete to retain a key the plan would otherwise destroy.
+var ErrVetoed = errors.New("vetoed by hook")
+
+//Hook observes the operations performed while applying a plan.  Any error other than a veto fails the apply with an
+//*ApplyError describing the changes made so far.
+type Hook interface {
+	//BeforeCreate is invoked before a replacement key is created.  An error prevents creation.
+	BeforeCreate(ctx context.Context, plan *KeyRotationPlan) error
+	//AfterCreate is invoked with the newly created key, before any keys are disabled or destroyed.  An error discards
+	//the new key, destroying it so the next rotation creates a replacement.
+	AfterCreate(ctx context.Context, plan *KeyRotationPlan, key Key) error
+	//BeforeDelete is invoked before each key is destroyed.  Returning ErrVetoed retains the key.
+	BeforeDelete(ctx context.Context, plan *KeyRotationPlan, key Key) error
+	//AfterApply is invoked with the healthy keys once all operations have been performed.
+	AfterApply(ctx context.Context, plan *KeyRotationPlan, keys KeyList) error
+}
+
+//Hooks invokes each hook in order, stopping at the first error.
+type Hooks []Hook
+
+func (h Hooks) BeforeCreate(ctx context.Context, plan *KeyRotationPlan) error {
+	for _, hook := range h {
+		if err := hook.BeforeCreate(ctx, plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Hooks) AfterCreate(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+	for _, hook := range h {
+		if err := hook.AfterCreate(ctx, plan, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Hooks) BeforeDelete(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+	for _, hook := range h {
+		if err := hook.BeforeDelete(ctx, plan, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Hooks) AfterApply(ctx context.Context, plan *KeyRotationPlan, keys KeyList) error {
+	for _, hook := range h {
+		if err := hook.AfterApply(ctx, plan, keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//HookFuncs adapts individual functions into a Hook.  nil functions are skipped.
+type HookFuncs struct {
+	OnBeforeCreate func(ctx context.Context, plan *KeyRotationPlan) error
+	OnAfterCreate  func(ctx context.Context, plan *KeyRotationPlan, key Key) error
+	OnBeforeDelete func(ctx context.Context, plan *KeyRotationPlan, key Key) error
+	OnAfterApply   func(ctx context.Context, plan *KeyRotationPlan, keys KeyList) error
+}
+
+func (h *HookFuncs) BeforeCreate(ctx context.Context, plan *KeyRotationPlan) error {
+	if h.OnBeforeCreate == nil {
+		return nil
+	}
+	return h.OnBeforeCreate(ctx, plan)
+}
+
+func (h *HookFuncs) AfterCreate(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+	if h.OnAfterCreate == nil {
+		return nil
+	}
+	return h.OnAfterCreate(ctx, plan, key)
+}
+
+func (h *HookFuncs) BeforeDelete(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+	if h.OnBeforeDelete == nil {
+		return nil
+	}
+	return h.OnBeforeDelete(ctx, plan, key)
+}
+
+func (h *HookFuncs) AfterApply(ctx context.Context, plan *KeyRotationPlan, keys KeyList) error {
+	if h.OnAfterApply == nil {
+		return nil
+	}
+	return h.OnAfterApply(ctx, plan, keys)
+}
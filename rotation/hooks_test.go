@@ -0,0 +1,158 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+//recordingHook appends each invocation to the events of the store so hooks may be ordered against store operations.
+func recordingHook(store *mockKeyStore) *HookFuncs {
+	return &HookFuncs{
+		OnBeforeCreate: func(ctx context.Context, plan *KeyRotationPlan) error {
+			store.events = append(store.events, "before-create")
+			return nil
+		},
+		OnAfterCreate: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			store.events = append(store.events, "after-create "+key.(*mockKey).id)
+			return nil
+		},
+		OnBeforeDelete: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			store.events = append(store.events, "before-delete "+key.(*mockKey).id)
+			return nil
+		},
+		OnAfterApply: func(ctx context.Context, plan *KeyRotationPlan, keys KeyList) error {
+			store.events = append(store.events, fmt.Sprintf("after-apply %d", len(keys)))
+			return nil
+		},
+	}
+}
+
+func TestHooksInvokedAroundOperations(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 2
+	expired := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{expired},
+		Hooks:       Hooks{recordingHook(store)},
+	}
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+
+	store.assertEvents(t, "before-create", "create mock-2", "after-create mock-2", "before-delete mock-1", "delete mock-1", "after-apply 1")
+}
+
+func TestHookVetoRetainsKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	expired := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		DestroyKeys: KeyList{expired},
+		Hooks: Hooks{&HookFuncs{OnBeforeDelete: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			return fmt.Errorf("still deployed: %w", ErrVetoed)
+		}}},
+	}
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+	store.assertEvents(t)
+}
+
+func TestHookVetoAtCapacityPreventsCreation(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.maximumCount = 1
+	expired := store.mockExpired(5)
+	plan := KeyRotationPlan{
+		CreateKey:      true,
+		DestroyKeys:    KeyList{expired},
+		Classification: Classification{{Key: expired, State: KeyExpired}},
+		Hooks: Hooks{&HookFuncs{OnBeforeDelete: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			return ErrVetoed
+		}}},
+	}
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("Expected an ApplyError, got %v", err)
+	}
+	assertKeyListSize(t, applyErr.Vetoed, 1)
+	if !errors.Is(err, ErrNoCapacity) || !errors.Is(err, ErrVetoed) {
+		t.Errorf("Expected lack of capacity caused by the veto, got %v", err)
+	}
+	store.assertNoKeysCreated(t)
+	store.assertEvents(t)
+}
+
+func TestHookErrorFailsApply(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	expired := store.mockExpired(5)
+	failure := errors.New("secret store unavailable")
+	plan := KeyRotationPlan{
+		CreateKey:   true,
+		DestroyKeys: KeyList{expired},
+		Hooks: Hooks{&HookFuncs{OnAfterCreate: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			return failure
+		}}},
+	}
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || !errors.Is(err, failure) {
+		t.Fatalf("Expected an ApplyError caused by the hook, got %v", err)
+	}
+	if applyErr.Created != nil || applyErr.Discarded == nil || !applyErr.Remaining.CreateKey {
+		t.Errorf("Expected the rejected key to be discarded, got %+v", applyErr)
+	}
+	assertKeyListSize(t, applyErr.Remaining.DestroyKeys, 1)
+	store.assertEvents(t, "create mock-2", "delete mock-2")
+}
+
+func TestHookErrorReportsUndiscardedKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.deleteErr = errors.New("access denied")
+	failure := errors.New("key never became usable")
+	plan := KeyRotationPlan{
+		CreateKey: true,
+		Hooks: Hooks{&HookFuncs{OnAfterCreate: func(ctx context.Context, plan *KeyRotationPlan, key Key) error {
+			return failure
+		}}},
+	}
+	_, err := plan.Apply(ctx, store)
+
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) || !errors.Is(err, failure) {
+		t.Fatalf("Expected an ApplyError caused by the hook, got %v", err)
+	}
+	if applyErr.Created == nil || applyErr.Discarded != nil {
+		t.Errorf("Expected the key which could not be discarded to be reported as created, got %+v", applyErr)
+	}
+}
+
+func TestExecutorRegistersHooks(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	executor := testExecutor()
+	executor.Hooks = Hooks{recordingHook(store)}
+	result := executor.Run(ctx, []Target{{Name: "alice", Store: store}})
+	assertNoError(t, result.Err())
+
+	store.assertEvents(t, "before-create", "create mock-1", "after-create mock-1", "after-apply 1")
+}
@@ -0,0 +1,88 @@
+package rotation
+
+import (
+	"slices"
+	"strings"
+)
+
+//KeyList is a set of keys as listed by a KeyStore, in the order provided by the store.  Operations produce new lists
+//rather than modifying the list in place.
+type KeyList []Key
+
+//KeyClassifier is implemented by rotation policies able to determine the state of keys already listed from a store.
+type KeyClassifier interface {
+	ClassifyKeys(keys KeyList) Classification
+}
+
+//compareCreated orders keys from oldest to newest.  Keys created at the same time are ordered by ID so decisions such
+//as which key to evict do not depend upon the order a store lists keys.
+func compareCreated(a, b Key) int {
+	if c := a.Created().Compare(b.Created()); c != 0 {
+		return c
+	}
+	aID, _ := IDOf(a)
+	bID, _ := IDOf(b)
+	return strings.Compare(aID, bID)
+}
+
+//SortByCreated produces a copy of the list ordered from oldest to newest.  Keys created at the same time are ordered
+//by ID, then by their position within the list.
+func (k KeyList) SortByCreated() KeyList {
+	sorted := slices.Clone(k)
+	slices.SortStableFunc(sorted, compareCreated)
+	return sorted
+}
+
+//Newest is the most recently created key, false if the list is empty.
+func (k KeyList) Newest() (Key, bool) {
+	if len(k) == 0 {
+		return nil, false
+	}
+	return slices.MaxFunc(k, compareCreated), true
+}
+
+//Oldest is the key created the longest ago, false if the list is empty.
+func (k KeyList) Oldest() (Key, bool) {
+	if len(k) == 0 {
+		return nil, false
+	}
+	return slices.MinFunc(k, compareCreated), true
+}
+
+//Filter produces the keys satisfying the predicate, preserving order.
+func (k KeyList) Filter(predicate func(Key) bool) KeyList {
+	out := make(KeyList, 0, len(k))
+	for _, key := range k {
+		if predicate(key) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+//Partition groups the keys by the state the policy classifies them in, preserving order within each state.
+func (k KeyList) Partition(policy KeyClassifier) map[KeyState]KeyList {
+	return policy.ClassifyKeys(k).Partition()
+}
+
+//ByID locates the key with the given ID, false if no key within the list has the ID.
+func (k KeyList) ByID(id string) (Key, bool) {
+	for _, key := range k {
+		if keyID, ok := IDOf(key); ok && keyID == id {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+//Contains determines if the exact key is within the list.
+func (k KeyList) Contains(key Key) bool {
+	return slices.Contains(k, key)
+}
+
+//Without produces a copy of the list excluding the given keys.
+func (k KeyList) Without(keys KeyList) KeyList {
+	return k.Filter(func(key Key) bool {
+		return !keys.Contains(key)
+	})
+}
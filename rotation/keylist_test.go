@@ -0,0 +1,117 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func keyIDs(keys KeyList) []string {
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = describeKey(k)
+	}
+	return out
+}
+
+func assertKeyIDs(t *testing.T, keys KeyList, expected ...string) {
+	t.Helper()
+	ids := keyIDs(keys)
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected keys %v, got %v", expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected keys %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestKeyListSortByCreated(t *testing.T) {
+	keys := KeyList{
+		&mockKey{id: "b", created: mockNow},
+		&mockKey{id: "c", created: mockNow.Add(-time.Hour)},
+		&mockKey{id: "a", created: mockNow},
+	}
+	sorted := keys.SortByCreated()
+	assertKeyIDs(t, sorted, "c", "a", "b")
+	assertKeyIDs(t, keys, "b", "c", "a")
+
+	newest, ok := keys.Newest()
+	if !ok || describeKey(newest) != "b" {
+		t.Errorf("Expected newest key b, got %+v", newest)
+	}
+	oldest, ok := keys.Oldest()
+	if !ok || describeKey(oldest) != "c" {
+		t.Errorf("Expected oldest key c, got %+v", oldest)
+	}
+	if _, ok := (KeyList{}).Newest(); ok {
+		t.Error("Expected no newest key of an empty list")
+	}
+	if _, ok := (KeyList{}).Oldest(); ok {
+		t.Error("Expected no oldest key of an empty list")
+	}
+}
+
+func TestKeyListFilterAndWithout(t *testing.T) {
+	active := &mockKey{id: "active", created: mockNow}
+	inactive := &mockKey{id: "inactive", created: mockNow, inactive: true}
+	other := &mockKey{id: "other", created: mockNow}
+	keys := KeyList{active, inactive, other}
+
+	assertKeyIDs(t, keys.Filter(IsActive), "active", "other")
+	assertKeyIDs(t, keys.Without(KeyList{active, other}), "inactive")
+	if !keys.Contains(other) || keys.Without(KeyList{other}).Contains(other) {
+		t.Error("Expected other to be removed")
+	}
+
+	if found, ok := keys.ByID("inactive"); !ok || found != inactive {
+		t.Errorf("Expected key to be found by ID, got %+v", found)
+	}
+	if _, ok := keys.ByID("missing"); ok {
+		t.Error("Expected no key to be found for an unknown ID")
+	}
+	if _, ok := (KeyList{&bareKey{created: mockNow}}).ByID(""); ok {
+		t.Error("Expected keys without IDs never to be found")
+	}
+}
+
+func TestKeyListPartition(t *testing.T) {
+	store := newMock()
+	valid := store.mockGoodKey()
+	grace := store.mockInGrace()
+	expired := store.mockExpired(5)
+	otherGrace := store.mockInGrace()
+
+	policy, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)))
+	assertNoError(t, err)
+	states := store.keys.Partition(policy)
+
+	assertKeyIDs(t, states[KeyValid], valid.id)
+	assertKeyIDs(t, states[KeyGrace], grace.id, otherGrace.id)
+	assertKeyIDs(t, states[KeyExpired], expired.id)
+	if len(states[KeyDisabled]) != 0 {
+		t.Errorf("Expected no disabled keys, got %v", keyIDs(states[KeyDisabled]))
+	}
+}
+
+func TestEvictionIgnoresListingOrder(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	created := mockNow.Add(-45 * time.Second)
+	first := &mockKey{id: "mock-1", created: created}
+	second := &mockKey{id: "mock-2", created: created}
+	for _, listed := range []KeyList{{first, second}, {second, first}} {
+		store := newMock()
+		store.maximumCount = 2
+		store.keys = listed
+
+		rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)))
+		assertNoError(t, err)
+		plan, err := rotation.Plan(ctx, store)
+		assertNoError(t, err)
+		if plan.Eviction == nil || plan.Eviction.Key != first {
+			t.Errorf("Expected %s to be evicted when listed as %v, got %+v", first.id, keyIDs(listed), plan.Eviction)
+		}
+	}
+}
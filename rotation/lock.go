@@ -0,0 +1,303 @@
+package rotation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//ErrNotLocked is returned when changing a LockingKeyStore without holding an unexpired lease.
+var ErrNotLocked = errors.New("lock not held")
+
+//LockHeldError reports a target locked by another holder.
+type LockHeldError struct {
+	Target string
+	//Holder describes who holds the lock, if known.
+	Holder string
+	//Expires is when the lease lapses and the lock may be taken over.
+	Expires time.Time
+}
+
+func (l *LockHeldError) Error() string {
+	return fmt.Sprintf("%s is locked by %s until %s", l.Target, l.Holder, l.Expires.Format(time.RFC3339))
+}
+
+//Lease is an exclusive lock on a target which lapses at Expires unless released sooner.
+type Lease interface {
+	Expires() time.Time
+	Release(ctx context.Context) error
+}
+
+//Locker provides exclusive leases on targets shared between processes.
+type Locker interface {
+	//Acquire obtains a lease on the target for the given duration.  Fails with *LockHeldError while another holder has
+	//an unexpired lease.
+	Acquire(ctx context.Context, target string, duration time.Duration) (Lease, error)
+}
+
+//Lockable is implemented by stores which must be locked before they are changed.
+type Lockable interface {
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+//LockingKeyStore refuses changes to the wrapped store unless a lease on the target is held, preventing concurrent
+//rotations from each creating or destroying keys based on the same view of the store.  Callers lock the store before
+//planning and unlock once the plan has been applied.  Listing keys does not require the lock.
+type LockingKeyStore struct {
+	KeyStoreDecorator
+	locker   Locker
+	target   string
+	duration time.Duration
+	clock    Clock
+
+	lock  sync.Mutex
+	lease Lease
+}
+
+//NewLockingKeyStore wraps the store, guarding each change to its keys with leases on the target lasting the given
+//duration.
+func NewLockingKeyStore(store KeyStore, locker Locker, target string, duration time.Duration) KeyStore {
+	locking := &LockingKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		locker:            locker,
+		target:            target,
+		duration:          duration,
+		clock:             SystemClock,
+	}
+	return PreserveCapabilities(locking)
+}
+
+//Lock acquires a lease on the target.
+func (l *LockingKeyStore) Lock(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.lease != nil {
+		return fmt.Errorf("%s is already locked", l.target)
+	}
+	lease, err := l.locker.Acquire(ctx, l.target, l.duration)
+	if err != nil {
+		return err
+	}
+	l.lease = lease
+	return nil
+}
+
+//Unlock releases the lease on the target.
+func (l *LockingKeyStore) Unlock(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.lease == nil {
+		return ErrNotLocked
+	}
+	err := l.lease.Release(ctx)
+	l.lease = nil
+	return err
+}
+
+//guard ensures the lease is held and has not lapsed before a change is made.
+func (l *LockingKeyStore) guard() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.lease == nil {
+		return fmt.Errorf("changing %s: %w", l.target, ErrNotLocked)
+	}
+	if !l.clock.Now().Before(l.lease.Expires()) {
+		return fmt.Errorf("changing %s: lease expired at %s: %w", l.target, l.lease.Expires().Format(time.RFC3339), ErrNotLocked)
+	}
+	return nil
+}
+
+func (l *LockingKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	if err := l.guard(); err != nil {
+		return nil, err
+	}
+	return l.Wrapped.CreateKey(ctx)
+}
+
+func (l *LockingKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	if err := l.guard(); err != nil {
+		return err
+	}
+	return l.Wrapped.DeleteKey(ctx, key)
+}
+
+func (l *LockingKeyStore) DisableKey(ctx context.Context, key Key) error {
+	if err := l.guard(); err != nil {
+		return err
+	}
+	return disableWith(ctx, l.Wrapped, key)
+}
+
+func (l *LockingKeyStore) EnableKey(ctx context.Context, key Key) error {
+	if err := l.guard(); err != nil {
+		return err
+	}
+	return enableWith(ctx, l.Wrapped, key)
+}
+
+//NewLockToken produces a random token distinguishing one lease from another.
+func NewLockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+//FileLocker provides leases between processes sharing a file system using one lock file per target within Directory.
+type FileLocker struct {
+	Directory string
+	//Holder describes this process within lock files, such as a host and user name.
+	Holder string
+	clock  Clock
+}
+
+//NewFileLocker creates a locker storing lock files within the directory.
+func NewFileLocker(directory string, holder string) *FileLocker {
+	return &FileLocker{Directory: directory, Holder: holder, clock: SystemClock}
+}
+
+//lockFile is the content of a lock file.
+type lockFile struct {
+	Holder  string    `json:"holder"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+func (f *FileLocker) path(target string) string {
+	return filepath.Join(f.Directory, fmt.Sprintf("%x.lock", target))
+}
+
+func (f *FileLocker) Acquire(ctx context.Context, target string, duration time.Duration) (Lease, error) {
+	if err := os.MkdirAll(f.Directory, 0700); err != nil {
+		return nil, err
+	}
+	token, err := NewLockToken()
+	if err != nil {
+		return nil, err
+	}
+	contents := lockFile{Holder: f.Holder, Token: token, Expires: f.clock.Now().Add(duration)}
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	path := f.path(target)
+	//The lock is written in full to a temporary file then linked into place, so the lock file is never observed empty
+	//or partially written.
+	pending := fmt.Sprintf("%s.%s.tmp", path, token)
+	if err := os.WriteFile(pending, data, 0600); err != nil {
+		_ = os.Remove(pending)
+		return nil, err
+	}
+	defer func() { _ = os.Remove(pending) }()
+
+	for {
+		err := os.Link(pending, path)
+		if err == nil {
+			return &fileLease{path: path, contents: contents}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		existing, err := observeLockFile(path, duration)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("reading lock for %s: %w", target, err)
+		}
+		if f.clock.Now().Before(existing.Expires) {
+			return nil, &LockHeldError{Target: target, Holder: existing.Holder, Expires: existing.Expires}
+		}
+		if err := takeOver(path, existing, token, duration); err != nil {
+			return nil, fmt.Errorf("taking over lapsed lock for %s: %w", target, err)
+		}
+	}
+}
+
+//takeOver removes the lapsed lock observed at the path so a new lock may be created.  Renaming is unconditional, so
+//another process which observed the same lapsed lock may have already replaced it with a live lock by the time the
+//file is moved.  The moved file is confirmed to be the lapsed lock, otherwise it is put back without replacing any
+//lock created in the meantime.
+func takeOver(path string, lapsed lockFile, token string, duration time.Duration) error {
+	moved := fmt.Sprintf("%s.%s.stale", path, token)
+	if err := os.Rename(path, moved); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(moved) }()
+
+	contents, err := observeLockFile(moved, duration)
+	if err == nil && contents.Token == lapsed.Token && contents.Expires.Equal(lapsed.Expires) {
+		return nil
+	}
+	if err := os.Link(moved, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+//unreadableHolder describes the holder of a lock file which can not be parsed.
+const unreadableHolder = "unknown holder (unreadable lock file)"
+
+//observeLockFile reads the lock file at the path.  A lock file which can not be parsed, such as one left truncated by
+//a crash, is considered held until the given duration has passed since it was last modified, so it eventually lapses
+//rather than locking the target forever.
+func observeLockFile(path string, duration time.Duration) (lockFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lockFile{}, err
+	}
+	var contents lockFile
+	if err := json.Unmarshal(data, &contents); err == nil {
+		return contents, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return lockFile{}, err
+	}
+	return lockFile{Holder: unreadableHolder, Expires: info.ModTime().Add(duration)}, nil
+}
+
+func readLockFile(path string) (lockFile, error) {
+	var contents lockFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return contents, err
+	}
+	err = json.Unmarshal(data, &contents)
+	return contents, err
+}
+
+type fileLease struct {
+	path     string
+	contents lockFile
+}
+
+func (f *fileLease) Expires() time.Time {
+	return f.contents.Expires
+}
+
+//Release removes the lock file if it still belongs to this lease.
+func (f *fileLease) Release(ctx context.Context) error {
+	existing, err := observeLockFile(f.path, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if existing.Token != f.contents.Token {
+		return nil
+	}
+	return os.Remove(f.path)
+}
@@ -0,0 +1,200 @@
+package rotation
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func testFileLocker(t *testing.T, holder string, at time.Time) *FileLocker {
+	locker := NewFileLocker(t.TempDir(), holder)
+	locker.clock = FixedClock(at)
+	return locker
+}
+
+func TestFileLockerRefusesHeldLock(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	first := testFileLocker(t, "first", mockNow)
+	second := NewFileLocker(first.Directory, "second")
+	second.clock = FixedClock(mockNow.Add(time.Minute))
+
+	lease, err := first.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+
+	_, err = second.Acquire(ctx, "alice", 5*time.Minute)
+	var held *LockHeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("Expected lock to be held, got %v", err)
+	}
+	if held.Holder != "first" || !held.Expires.Equal(mockNow.Add(5*time.Minute)) {
+		t.Errorf("Expected holder and expiry of first lease, got %+v", held)
+	}
+
+	_, err = second.Acquire(ctx, "bob", 5*time.Minute)
+	assertNoError(t, err)
+
+	assertNoError(t, lease.Release(ctx))
+	_, err = second.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+}
+
+func TestFileLockerTakesOverExpiredLease(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	first := testFileLocker(t, "first", mockNow)
+	second := NewFileLocker(first.Directory, "second")
+	second.clock = FixedClock(mockNow.Add(10 * time.Minute))
+
+	stale, err := first.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+	_, err = second.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+
+	//Releasing the lapsed lease must not remove the lock taken over by second.
+	assertNoError(t, stale.Release(ctx))
+	_, err = first.Acquire(ctx, "alice", 5*time.Minute)
+	var held *LockHeldError
+	if !errors.As(err, &held) || held.Holder != "second" {
+		t.Errorf("Expected lock to be held by second, got %v", err)
+	}
+}
+
+func TestFileLockerTakeOverRestoresLiveLock(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	first := testFileLocker(t, "first", mockNow)
+	second := NewFileLocker(first.Directory, "second")
+	second.clock = FixedClock(mockNow.Add(10 * time.Minute))
+
+	_, err := first.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+	path := first.path("alice")
+	lapsed, err := readLockFile(path)
+	assertNoError(t, err)
+	_, err = second.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+
+	//A third process observed the lapsed lock of first before second replaced it.
+	assertNoError(t, takeOver(path, lapsed, "third", 5*time.Minute))
+	live, err := readLockFile(path)
+	assertNoError(t, err)
+	if live.Holder != "second" {
+		t.Errorf("Expected the live lock of second to be restored, got %+v", live)
+	}
+}
+
+func TestFileLockerUnreadableLockLapsesByModification(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	//A process crashed while writing, leaving an empty lock file last modified at mockNow.
+	early := testFileLocker(t, "early", mockNow.Add(time.Minute))
+	path := early.path("alice")
+	assertNoError(t, os.WriteFile(path, nil, 0600))
+	assertNoError(t, os.Chtimes(path, mockNow, mockNow))
+
+	_, err := early.Acquire(ctx, "alice", 5*time.Minute)
+	var held *LockHeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("Expected an unreadable lock to be held, got %v", err)
+	}
+	if held.Holder != unreadableHolder || !held.Expires.Equal(mockNow.Add(5*time.Minute)) {
+		t.Errorf("Expected the unreadable lock to expire after the lease duration, got %+v", held)
+	}
+
+	late := NewFileLocker(early.Directory, "late")
+	late.clock = FixedClock(mockNow.Add(10 * time.Minute))
+	_, err = late.Acquire(ctx, "alice", 5*time.Minute)
+	assertNoError(t, err)
+	live, err := readLockFile(path)
+	assertNoError(t, err)
+	if live.Holder != "late" {
+		t.Errorf("Expected the unreadable lock to be taken over, got %+v", live)
+	}
+	entries, err := os.ReadDir(early.Directory)
+	assertNoError(t, err)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the lock file to remain, got %d entries", len(entries))
+	}
+}
+
+func TestLockingKeyStoreRequiresLock(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	existing := store.mockGoodKey()
+	locking := NewLockingKeyStore(store, testFileLocker(t, "test", mockNow), "alice", time.Minute)
+	locking.(*LockingKeyStore).clock = FixedClock(mockNow)
+
+	if _, err := locking.CreateKey(ctx); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("Expected creation to require the lock, got %v", err)
+	}
+	if _, err := locking.ListKeys(ctx); err != nil {
+		t.Errorf("Expected listing without the lock, got %v", err)
+	}
+	store.assertNoKeysCreated(t)
+
+	lockable := locking.(Lockable)
+	assertNoError(t, lockable.Lock(ctx))
+	_, err := locking.CreateKey(ctx)
+	assertNoError(t, err)
+	store.assertCreatedKey(t)
+
+	assertNoError(t, lockable.Unlock(ctx))
+	if err := locking.(DisablingKeyStore).DisableKey(ctx, existing); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("Expected disabling to require the lock, got %v", err)
+	}
+}
+
+func TestLockingKeyStoreRefusesExpiredLease(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	locking := NewLockingKeyStore(store, testFileLocker(t, "test", mockNow), "alice", time.Minute).(*LockingKeyStore)
+	locking.clock = FixedClock(mockNow.Add(2 * time.Minute))
+
+	assertNoError(t, locking.Lock(ctx))
+	if _, err := locking.CreateKey(ctx); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("Expected creation to be refused after the lease expired, got %v", err)
+	}
+	store.assertNoKeysCreated(t)
+}
+
+func TestExecutorLocksTargets(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	locker := testFileLocker(t, "test", time.Now())
+	held, err := locker.Acquire(ctx, "held", time.Minute)
+	assertNoError(t, err)
+	defer held.Release(ctx)
+
+	heldStore := newMock()
+	freeStore := newMock()
+	result := testExecutor().Run(ctx, []Target{
+		{Name: "held", Store: NewLockingKeyStore(heldStore, locker, "held", time.Minute)},
+		{Name: "free", Store: NewLockingKeyStore(freeStore, locker, "free", time.Minute)},
+	})
+
+	var heldErr *LockHeldError
+	if !errors.As(result.Results[0].Err, &heldErr) {
+		t.Errorf("Expected held target to fail on the lock, got %+v", result.Results[0])
+	}
+	heldStore.assertNoKeysCreated(t)
+	if result.Results[1].Outcome != OutcomeRotated {
+		t.Errorf("Expected free target to be rotated, got %+v", result.Results[1])
+	}
+	freeStore.assertCreatedKey(t)
+
+	//The free target's lock is released once applied.
+	lease, err := locker.Acquire(ctx, "free", time.Minute)
+	assertNoError(t, err)
+	assertNoError(t, lease.Release(ctx))
+}
@@ -0,0 +1,250 @@
+package rotation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Metrics collects per-target key ages and rotation outcomes, rendered in the Prometheus text exposition format.  Safe
+//for concurrent use.
+type Metrics struct {
+	lock    sync.Mutex
+	targets map[string]*targetMetrics
+}
+
+//targetMetrics are the observations for a single target.
+type targetMetrics struct {
+	//observed is true once a classification has been recorded for the target.
+	observed bool
+	//newestValidAge is the age in seconds of the newest valid key, negative without a valid key.
+	newestValidAge float64
+	//untilGrace is the seconds until the newest valid key enters its grace period.
+	untilGrace float64
+	keys       map[KeyState]int
+	//operations counts store operations by kind and outcome.
+	operations map[operationOutcome]int
+	//runs counts rotations by outcome.
+	runs map[Outcome]int
+}
+
+type operationOutcome struct {
+	kind    OperationKind
+	outcome AuditOutcome
+}
+
+//NewMetrics creates an empty collection.
+func NewMetrics() *Metrics {
+	return &Metrics{targets: make(map[string]*targetMetrics)}
+}
+
+func (m *Metrics) target(name string) *targetMetrics {
+	t, ok := m.targets[name]
+	if !ok {
+		t = &targetMetrics{
+			keys:       make(map[KeyState]int),
+			operations: make(map[operationOutcome]int),
+			runs:       make(map[Outcome]int),
+		}
+		m.targets[name] = t
+	}
+	return t
+}
+
+//ObservePlan records the state of the keys of the plan's target as of planning.
+func (m *Metrics) ObservePlan(plan *KeyRotationPlan) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t := m.target(plan.Target)
+	t.observed = true
+	t.newestValidAge, t.untilGrace = -1, 0
+	t.keys = make(map[KeyState]int)
+	for _, c := range plan.Classification {
+		t.keys[c.State]++
+	}
+	valid := plan.Classification.InState(KeyValid).SortByCreated()
+	if len(valid) > 0 {
+		newest := valid[len(valid)-1]
+		t.newestValidAge = newest.Age.Seconds()
+		t.untilGrace = newest.Remaining.Seconds()
+	}
+}
+
+//ObserveOperation counts a store operation against the target.
+func (m *Metrics) ObserveOperation(target string, kind OperationKind, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	outcome := AuditSuccess
+	if err != nil {
+		outcome = AuditFailure
+	}
+	m.target(target).operations[operationOutcome{kind: kind, outcome: outcome}]++
+}
+
+//ObserveRun counts the outcome of rotating the target.
+func (m *Metrics) ObserveRun(result TargetResult) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.target(result.Target).runs[result.Outcome]++
+}
+
+//WriteTo renders all metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make([]string, 0, len(m.targets))
+	for name := range m.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	family := func(name, kind, help string, samples func()) {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		samples()
+	}
+	family("key_rotation_newest_valid_key_age_seconds", "gauge", "Age of the newest valid key, -1 without a valid key.", func() {
+		for _, name := range names {
+			if t := m.targets[name]; t.observed {
+				fmt.Fprintf(&out, "key_rotation_newest_valid_key_age_seconds%s %g\n", labels("target", name), t.newestValidAge)
+			}
+		}
+	})
+	family("key_rotation_seconds_until_grace", "gauge", "Time until the newest valid key enters its grace period.", func() {
+		for _, name := range names {
+			if t := m.targets[name]; t.observed {
+				fmt.Fprintf(&out, "key_rotation_seconds_until_grace%s %g\n", labels("target", name), t.untilGrace)
+			}
+		}
+	})
+	family("key_rotation_keys", "gauge", "Number of keys in each state.", func() {
+		for _, name := range names {
+			if t := m.targets[name]; t.observed {
+				for _, state := range []KeyState{KeyValid, KeyGrace, KeyDisabled, KeyExpired} {
+					fmt.Fprintf(&out, "key_rotation_keys%s %d\n", labels("target", name, "state", string(state)), t.keys[state])
+				}
+			}
+		}
+	})
+	family("key_rotation_operations_total", "counter", "Key store operations performed by outcome.", func() {
+		for _, name := range names {
+			t := m.targets[name]
+			operations := make([]operationOutcome, 0, len(t.operations))
+			for o := range t.operations {
+				operations = append(operations, o)
+			}
+			sort.Slice(operations, func(i, j int) bool {
+				if operations[i].kind != operations[j].kind {
+					return operations[i].kind < operations[j].kind
+				}
+				return operations[i].outcome < operations[j].outcome
+			})
+			for _, o := range operations {
+				fmt.Fprintf(&out, "key_rotation_operations_total%s %d\n", labels("target", name, "operation", string(o.kind), "outcome", string(o.outcome)), t.operations[o])
+			}
+		}
+	})
+	family("key_rotation_runs_total", "counter", "Rotations of each target by outcome.", func() {
+		for _, name := range names {
+			t := m.targets[name]
+			for _, outcome := range []Outcome{OutcomeUnchanged, OutcomeRotated, OutcomePlanned, OutcomeFailed} {
+				if count, ok := t.runs[outcome]; ok {
+					fmt.Fprintf(&out, "key_rotation_runs_total%s %d\n", labels("target", name, "outcome", string(outcome)), count)
+				}
+			}
+		}
+	})
+	return out.WriteTo(w)
+}
+
+//labelEscaper escapes label values as required by the exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+//labels renders the label set from alternating names and values.
+func labels(pairs ...string) string {
+	rendered := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rendered = append(rendered, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(strings.ToValidUTF8(pairs[i+1], "\uFFFD"))))
+	}
+	return "{" + strings.Join(rendered, ",") + "}"
+}
+
+//ServeHTTP exposes the metrics for scraping, such as on /metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = m.WriteTo(w)
+}
+
+//WriteTextfile atomically replaces the file with the current metrics, suitable for the node exporter's textfile
+//collector.
+func (m *Metrics) WriteTextfile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := m.WriteTo(tmp); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//MetricsKeyStore counts each change made to the wrapped store.
+type MetricsKeyStore struct {
+	KeyStoreDecorator
+	metrics *Metrics
+	target  string
+}
+
+//NewMetricsKeyStore wraps the store, counting each change to its keys against the target.
+func NewMetricsKeyStore(store KeyStore, metrics *Metrics, target string) KeyStore {
+	counting := &MetricsKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		metrics:           metrics,
+		target:            target,
+	}
+	return PreserveCapabilities(counting)
+}
+
+func (m *MetricsKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	key, err := m.Wrapped.CreateKey(ctx)
+	m.metrics.ObserveOperation(m.target, OperationCreate, err)
+	return key, err
+}
+
+func (m *MetricsKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	err := m.Wrapped.DeleteKey(ctx, key)
+	m.metrics.ObserveOperation(m.target, OperationDelete, err)
+	return err
+}
+
+func (m *MetricsKeyStore) DisableKey(ctx context.Context, key Key) error {
+	err := disableWith(ctx, m.Wrapped, key)
+	m.metrics.ObserveOperation(m.target, OperationDisable, err)
+	return err
+}
+
+func (m *MetricsKeyStore) EnableKey(ctx context.Context, key Key) error {
+	err := enableWith(ctx, m.Wrapped, key)
+	m.metrics.ObserveOperation(m.target, OperationEnable, err)
+	return err
+}
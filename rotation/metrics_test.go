@@ -0,0 +1,79 @@
+package rotation
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assertMetric(t *testing.T, exposition string, sample string) {
+	t.Helper()
+	for _, line := range strings.Split(exposition, "\n") {
+		if line == sample {
+			return
+		}
+	}
+	t.Errorf("Expected sample %q within:\n%s", sample, exposition)
+}
+
+func TestMetricsRecordExecutorRuns(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	metrics := NewMetrics()
+	good := newMock()
+	good.mockGoodKey()
+	expiring := newMock()
+	expiring.mockExpired(5)
+	executor := testExecutor()
+	executor.Metrics = metrics
+	result := executor.Run(ctx, []Target{
+		{Name: "alice", Store: good},
+		{Name: "bob", Store: NewMetricsKeyStore(expiring, metrics, "bob")},
+	})
+	assertNoError(t, result.Err())
+
+	var out bytes.Buffer
+	_, err := metrics.WriteTo(&out)
+	assertNoError(t, err)
+	exposition := out.String()
+	assertMetric(t, exposition, `key_rotation_newest_valid_key_age_seconds{target="alice"} 0`)
+	assertMetric(t, exposition, `key_rotation_seconds_until_grace{target="alice"} 30`)
+	assertMetric(t, exposition, `key_rotation_newest_valid_key_age_seconds{target="bob"} -1`)
+	assertMetric(t, exposition, `key_rotation_keys{target="bob",state="expired"} 1`)
+	assertMetric(t, exposition, `key_rotation_operations_total{target="bob",operation="create",outcome="success"} 1`)
+	assertMetric(t, exposition, `key_rotation_operations_total{target="bob",operation="delete",outcome="success"} 1`)
+	assertMetric(t, exposition, `key_rotation_runs_total{target="alice",outcome="unchanged"} 1`)
+	assertMetric(t, exposition, `key_rotation_runs_total{target="bob",outcome="rotated"} 1`)
+	assertMetric(t, exposition, `# TYPE key_rotation_operations_total counter`)
+}
+
+func TestMetricsEscapeLabels(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.ObserveRun(TargetResult{Target: "a\"b\\c\nd", Outcome: OutcomeFailed})
+
+	var out bytes.Buffer
+	_, err := metrics.WriteTo(&out)
+	assertNoError(t, err)
+	assertMetric(t, out.String(), `key_rotation_runs_total{target="a\"b\\c\nd",outcome="failed"} 1`)
+}
+
+func TestMetricsServedAndWritten(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.ObserveRun(TargetResult{Target: "alice", Outcome: OutcomeRotated})
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assertMetric(t, recorder.Body.String(), `key_rotation_runs_total{target="alice",outcome="rotated"} 1`)
+
+	path := filepath.Join(t.TempDir(), "key_rotation.prom")
+	assertNoError(t, metrics.WriteTextfile(path))
+	written, err := os.ReadFile(path)
+	assertNoError(t, err)
+	if string(written) != recorder.Body.String() {
+		t.Errorf("Expected textfile to match the served metrics, got:\n%s", written)
+	}
+}
@@ -2,15 +2,20 @@ package rotation
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
+//mockNow is the reference time for all mock stores and policies under test.
+var mockNow = time.Date(2021, time.August, 1, 12, 0, 0, 0, time.UTC)
+
 func newMock() *mockKeyStore {
 	return &mockKeyStore{
 		keys:         make(KeyList, 0),
 		createdKey:   false,
 		deletedKeys:  nil,
 		maximumCount: 1024,
+		clock:        FixedClock(mockNow),
 	}
 }
 
@@ -48,19 +53,69 @@ type mockKeyStore struct {
 	createdKey   bool
 	deletedKeys  KeyList
 	maximumCount int
+	issued       int
+	clock        Clock
+	//events records the operations performed against the store in order.
+	events []string
+	//createErr when set causes CreateKey to fail.
+	createErr error
+	//deleteErr when set causes DeleteKey to fail.
+	deleteErr error
+	//snapshots when set causes ListKeys to produce copies of each key, as real stores do, so changes to the store are not
+	//reflected within keys listed earlier.
+	snapshots bool
 }
 
 func (m *mockKeyStore) CreateKey(ctx context.Context) (Key, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
 	m.createdKey = true
-	return &mockKey{created: time.Now()}, nil
+	key := &mockKey{id: m.nextID(), created: m.clock.Now()}
+	m.events = append(m.events, "create "+key.id)
+	return key, nil
 }
 
 func (m *mockKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
 	m.deletedKeys = append(m.deletedKeys, key)
+	m.events = append(m.events, "delete "+key.(*mockKey).id)
+	return nil
+}
+
+func (m *mockKeyStore) DisableKey(ctx context.Context, key Key) error {
+	m.stored(key).inactive = true
+	m.events = append(m.events, "disable "+key.(*mockKey).id)
 	return nil
 }
 
+func (m *mockKeyStore) EnableKey(ctx context.Context, key Key) error {
+	m.stored(key).inactive = false
+	m.events = append(m.events, "enable "+key.(*mockKey).id)
+	return nil
+}
+
+//stored finds the key held by the store with the identity of the given key, which may be a snapshot.
+func (m *mockKeyStore) stored(key Key) *mockKey {
+	for _, k := range m.keys {
+		if k.(*mockKey).id == key.(*mockKey).id {
+			return k.(*mockKey)
+		}
+	}
+	return key.(*mockKey)
+}
+
 func (m *mockKeyStore) ListKeys(ctx context.Context) (KeyList, error) {
+	if m.snapshots {
+		out := make(KeyList, len(m.keys))
+		for i, k := range m.keys {
+			snapshot := *k.(*mockKey)
+			out[i] = &snapshot
+		}
+		return out, nil
+	}
 	return m.keys, nil
 }
 
@@ -72,7 +127,7 @@ func (m *mockKeyStore) appendKeyExpiring(at int) *mockKey {
 	if len(m.keys) > m.maximumCount {
 		panic("exceeds maximum count")
 	}
-	key := &mockKey{created: time.Now().Add(-1 * time.Duration(at) * time.Second)}
+	key := &mockKey{id: m.nextID(), created: m.clock.Now().Add(-1 * time.Duration(at) * time.Second)}
 	m.keys = append(m.keys, key)
 	return key
 }
@@ -90,10 +145,30 @@ func (m *mockKeyStore) mockGoodKey() *mockKey {
 	return m.appendKeyExpiring(0)
 }
 
+func (m *mockKeyStore) nextID() string {
+	m.issued++
+	return fmt.Sprintf("mock-%d", m.issued)
+}
+
 type mockKey struct {
-	created time.Time
+	id       string
+	created  time.Time
+	lastUsed time.Time
+	inactive bool
 }
 
 func (m *mockKey) Created() time.Time {
 	return m.created
 }
+
+func (m *mockKey) KeyID() string {
+	return m.id
+}
+
+func (m *mockKey) Active() bool {
+	return !m.inactive
+}
+
+func (m *mockKey) LastUsed() (time.Time, bool) {
+	return m.lastUsed, !m.lastUsed.IsZero()
+}
@@ -0,0 +1,64 @@
+//Package oteltracing exports the spans of the rotation package through OpenTelemetry.
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//InstrumentationName identifies spans produced by the rotation package.
+const InstrumentationName = "github.com/truewhitespace/key-rotation/rotation"
+
+//Tracer adapts an OpenTelemetry tracer to rotation.Tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+//NewTracer creates a tracer producing spans through the provider.  Register it with rotation.SetTracer.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(InstrumentationName)}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attributes ...rotation.Attribute) (context.Context, rotation.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attributes)...))
+	return ctx, &otelSpan{span: span}
+}
+
+//otelSpan adapts an OpenTelemetry span to rotation.Span.
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attributes ...rotation.Attribute) {
+	s.span.SetAttributes(convert(attributes)...)
+}
+
+//End records only the error message, never the error itself.
+func (s *otelSpan) End(err error) {
+	if err != nil {
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func convert(attributes []rotation.Attribute) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, len(attributes))
+	for i, a := range attributes {
+		key := attribute.Key(a.Key)
+		switch v := a.Value.(type) {
+		case string:
+			out[i] = key.String(v)
+		case int:
+			out[i] = key.Int(v)
+		case bool:
+			out[i] = key.Bool(v)
+		default:
+			out[i] = key.String(fmt.Sprint(v))
+		}
+	}
+	return out
+}
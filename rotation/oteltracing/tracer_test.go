@@ -0,0 +1,55 @@
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracerExportsSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := tracer.Start(context.Background(), "parent", rotation.Attribute{Key: "target", Value: "alice"})
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttributes(
+		rotation.Attribute{Key: "keys", Value: 2},
+		rotation.Attribute{Key: "create", Value: true},
+	)
+	child.End(errors.New("boom"))
+	parent.End(nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	exportedChild, exportedParent := spans[0], spans[1]
+	if exportedChild.Parent().SpanID() != exportedParent.SpanContext().SpanID() {
+		t.Error("Expected the child span to be parented by the context")
+	}
+	if exportedChild.Status().Code != codes.Error || exportedChild.Status().Description != "boom" {
+		t.Errorf("Expected the child span to fail with the error message, got %+v", exportedChild.Status())
+	}
+	if exportedParent.Status().Code == codes.Error {
+		t.Error("Expected the parent span to succeed")
+	}
+
+	expected := []attribute.KeyValue{attribute.Int("keys", 2), attribute.Bool("create", true)}
+	if attributes := exportedChild.Attributes(); len(attributes) != len(expected) {
+		t.Errorf("Expected attributes %v, got %v", expected, attributes)
+	} else {
+		for i, a := range expected {
+			if attributes[i] != a {
+				t.Errorf("Expected attribute %v, got %v", a, attributes[i])
+			}
+		}
+	}
+	if target := exportedParent.Attributes(); len(target) != 1 || target[0] != attribute.String("target", "alice") {
+		t.Errorf("Expected the target attribute, got %v", target)
+	}
+}
@@ -0,0 +1,284 @@
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//planFileVersion is the revision of the persisted plan format produced by MarshalJSON.
+const planFileVersion = 1
+
+//planFile is the persisted form of a KeyRotationPlan.
+type planFile struct {
+	Version   int               `json:"version"`
+	Created   time.Time         `json:"created"`
+	Target    string            `json:"target,omitempty"`
+	Policy    Policy            `json:"policy"`
+	CreateKey bool              `json:"create_key"`
+	Deferred  *time.Time        `json:"create_deferred_until,omitempty"`
+	Keys      []planFileKey     `json:"keys"`
+	Eviction  *planFileEviction `json:"eviction,omitempty"`
+	Warnings  []string          `json:"warnings,omitempty"`
+	//MaximumDestructive is the cap on keys disabled and destroyed, enforced when the plan is applied.
+	MaximumDestructive int `json:"maximum_destructive_operations,omitempty"`
+}
+
+//planFileEviction records the grace key selected for destruction to free a slot.
+type planFileEviction struct {
+	ID     string `json:"id"`
+	Policy string `json:"policy"`
+	Reason string `json:"reason"`
+}
+
+//planFileKey records the identity and classification of a single key at the time of planning.
+type planFileKey struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	State     KeyState  `json:"state"`
+	Remaining string    `json:"remaining,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Inactive  bool      `json:"inactive,omitempty"`
+	Disable   bool      `json:"disable,omitempty"`
+	Destroy   bool      `json:"destroy"`
+}
+
+//savedKey is a placeholder for a key loaded from a persisted plan.  Saved keys must be rebound to the keys of a live
+//store before the plan may be applied.
+type savedKey struct {
+	id       string
+	created  time.Time
+	inactive bool
+}
+
+func (s *savedKey) Created() time.Time {
+	return s.created
+}
+
+func (s *savedKey) KeyID() string {
+	return s.id
+}
+
+func (s *savedKey) Active() bool {
+	return !s.inactive
+}
+
+//MarshalJSON persists the plan including the identity and classification of each key.  All keys must implement
+//IdentifiableKey.  Secret material is never included.
+func (plan *KeyRotationPlan) MarshalJSON() ([]byte, error) {
+	file := planFile{
+		Version:   planFileVersion,
+		Created:   plan.Created,
+		Target:    plan.Target,
+		Policy:    plan.Policy,
+		CreateKey: plan.CreateKey,
+		Keys:      make([]planFileKey, 0),
+		Warnings:  plan.Warnings,
+
+		MaximumDestructive: plan.MaximumDestructiveOperations,
+	}
+	if !plan.CreateDeferredUntil.IsZero() {
+		file.Deferred = &plan.CreateDeferredUntil
+	}
+
+	appendKey := func(c KeyClassification) error {
+		id, ok := IDOf(c.Key)
+		if !ok {
+			return fmt.Errorf("key %+v does not provide an identity and can not be persisted", c.Key)
+		}
+		entry := planFileKey{
+			ID:       id,
+			Created:  c.Key.Created(),
+			State:    c.State,
+			Reason:   c.Reason,
+			Inactive: !IsActive(c.Key),
+			Disable:  plan.DisableKeys.Contains(c.Key),
+			Destroy:  plan.destroying(c.Key),
+		}
+		if c.Remaining > 0 {
+			entry.Remaining = c.Remaining.String()
+		}
+		file.Keys = append(file.Keys, entry)
+		return nil
+	}
+
+	for _, c := range plan.Classification {
+		if err := appendKey(c); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range plan.DestroyKeys {
+		if _, ok := plan.Classification.Find(k); ok {
+			continue
+		}
+		if err := appendKey(KeyClassification{Key: k, State: KeyExpired}); err != nil {
+			return nil, err
+		}
+	}
+	if plan.Eviction != nil {
+		id, ok := IDOf(plan.Eviction.Key)
+		if !ok {
+			return nil, fmt.Errorf("evicted key %+v does not provide an identity and can not be persisted", plan.Eviction.Key)
+		}
+		file.Eviction = &planFileEviction{
+			ID:     id,
+			Policy: plan.Eviction.Policy,
+			Reason: plan.Eviction.Reason,
+		}
+	}
+	return json.Marshal(file)
+}
+
+//UnmarshalJSON loads a plan persisted by MarshalJSON.  The resulting plan refers to placeholder keys and must be
+//passed through Rebind before being applied.
+func (plan *KeyRotationPlan) UnmarshalJSON(data []byte) error {
+	var file planFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if file.Version != planFileVersion {
+		return fmt.Errorf("unsupported plan version %d", file.Version)
+	}
+
+	loaded := KeyRotationPlan{
+		CreateKey:      file.CreateKey,
+		DisableKeys:    make(KeyList, 0),
+		DestroyKeys:    make(KeyList, 0),
+		Target:         file.Target,
+		Policy:         file.Policy,
+		Created:        file.Created,
+		Classification: make(Classification, 0, len(file.Keys)),
+		Warnings:       file.Warnings,
+
+		MaximumDestructiveOperations: file.MaximumDestructive,
+	}
+	if file.Deferred != nil {
+		loaded.CreateDeferredUntil = *file.Deferred
+	}
+	for _, k := range file.Keys {
+		switch k.State {
+		case KeyValid, KeyGrace, KeyDisabled, KeyExpired:
+		default:
+			return fmt.Errorf("key %q has unknown state %q", k.ID, k.State)
+		}
+
+		key := &savedKey{id: k.ID, created: k.Created, inactive: k.Inactive}
+		entry := KeyClassification{
+			Key:    key,
+			State:  k.State,
+			Age:    file.Created.Sub(k.Created),
+			Reason: k.Reason,
+		}
+		if k.Remaining != "" {
+			remaining, err := time.ParseDuration(k.Remaining)
+			if err != nil {
+				return fmt.Errorf("key %q has bad remaining time: %w", k.ID, err)
+			}
+			entry.Remaining = remaining
+		}
+		loaded.Classification = append(loaded.Classification, entry)
+		if k.Disable {
+			loaded.DisableKeys = append(loaded.DisableKeys, key)
+		}
+		if k.Destroy {
+			loaded.DestroyKeys = append(loaded.DestroyKeys, key)
+		}
+		if file.Eviction != nil && file.Eviction.ID == k.ID {
+			loaded.Eviction = &Eviction{Key: key, Policy: file.Eviction.Policy, Reason: file.Eviction.Reason}
+		}
+	}
+	if file.Eviction != nil && loaded.Eviction == nil {
+		return fmt.Errorf("evicted key %q is not within the plan", file.Eviction.ID)
+	}
+	*plan = loaded
+	return nil
+}
+
+//Rebind reconciles the plan against the current keys within the store, producing a plan which operates on the live
+//keys.  Rebind fails if any key has been added, removed, or altered since the plan was produced, or if the plan exceeds
+//its MaximumDestructiveOperations.  Keys classified as disabled which the plan neither disables nor destroys may have
+//been deactivated since, as when resuming the remaining plan of an ApplyError.
+func (plan *KeyRotationPlan) Rebind(ctx context.Context, store KeyStore) (*KeyRotationPlan, error) {
+	if err := plan.checkDestructiveLimit(); err != nil {
+		return nil, err
+	}
+	liveKeys, err := store.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Key, len(liveKeys))
+	for _, k := range liveKeys {
+		id, ok := IDOf(k)
+		if !ok {
+			return nil, fmt.Errorf("key %+v does not provide an identity and can not be reconciled", k)
+		}
+		byID[id] = k
+	}
+
+	//rebind finds the live key, allowing keys the plan has already disabled, such as those within the remaining plan of
+	//an ApplyError, to have been deactivated since they were listed.
+	rebind := func(k Key, disabled bool) (Key, error) {
+		id, ok := IDOf(k)
+		if !ok {
+			return nil, fmt.Errorf("key %+v does not provide an identity and can not be reconciled", k)
+		}
+		live, ok := byID[id]
+		if !ok {
+			return nil, &StalePlanError{KeyID: id, Reason: "no longer exists"}
+		}
+		if !live.Created().Equal(k.Created()) || (IsActive(live) != IsActive(k) && !(disabled && !IsActive(live))) {
+			return nil, &StalePlanError{KeyID: id, Reason: "has changed since planning"}
+		}
+		return live, nil
+	}
+
+	result := &KeyRotationPlan{
+		CreateKey:           plan.CreateKey,
+		CreateDeferredUntil: plan.CreateDeferredUntil,
+		DisableKeys:         make(KeyList, len(plan.DisableKeys)),
+		DestroyKeys:         make(KeyList, len(plan.DestroyKeys)),
+		Target:              plan.Target,
+		Policy:              plan.Policy,
+		Created:             plan.Created,
+		Classification:      make(Classification, len(plan.Classification)),
+		Warnings:            plan.Warnings,
+		Hooks:               plan.Hooks,
+
+		MaximumDestructiveOperations: plan.MaximumDestructiveOperations,
+	}
+	for i, c := range plan.Classification {
+		disabled := c.State == KeyDisabled && !plan.DisableKeys.Contains(c.Key) && !plan.destroying(c.Key)
+		if c.Key, err = rebind(c.Key, disabled); err != nil {
+			return nil, err
+		}
+		result.Classification[i] = c
+	}
+	for i, k := range plan.DisableKeys {
+		if result.DisableKeys[i], err = rebind(k, false); err != nil {
+			return nil, err
+		}
+	}
+	for i, k := range plan.DestroyKeys {
+		if result.DestroyKeys[i], err = rebind(k, false); err != nil {
+			return nil, err
+		}
+	}
+	if plan.Eviction != nil {
+		eviction := *plan.Eviction
+		if eviction.Key, err = rebind(eviction.Key, false); err != nil {
+			return nil, err
+		}
+		result.Eviction = &eviction
+	}
+	if planned := result.keyCount(); planned != len(liveKeys) {
+		return nil, &StalePlanError{Reason: fmt.Sprintf("store has %d keys, plan accounts for %d", len(liveKeys), planned)}
+	}
+	return result, nil
+}
+
+//destroying determines if the given key is scheduled for destruction.
+func (plan *KeyRotationPlan) destroying(key Key) bool {
+	return plan.DestroyKeys.Contains(key)
+}
@@ -0,0 +1,175 @@
+package rotation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func roundTripPlan(t *testing.T, plan *KeyRotationPlan) *KeyRotationPlan {
+	t.Helper()
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to marshal plan because %s", err.Error())
+	}
+	loaded := &KeyRotationPlan{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatalf("Failed to unmarshal plan because %s", err.Error())
+	}
+	return loaded
+}
+
+func planFor(t *testing.T, store KeyStore) *KeyRotationPlan {
+	t.Helper()
+	ctx, done := testContext(t)
+	defer done()
+
+	rotation, err := NewGracefulExpiration(60*time.Second, 30*time.Second, WithClock(FixedClock(mockNow)))
+	assertNoError(t, err)
+	plan, err := rotation.Plan(ctx, store)
+	if err != nil {
+		t.Fatalf("Failed planning because %s", err.Error())
+	}
+	return plan
+}
+
+func TestPlanRoundTripPreservesClassification(t *testing.T) {
+	store := newMock()
+	store.mockGoodKey()
+	store.mockInGrace()
+	store.mockExpired(5)
+
+	plan := planFor(t, store)
+	loaded := roundTripPlan(t, plan)
+
+	assertKeyListSize(t, loaded.Classification.Keys(KeyValid), 1)
+	assertKeyListSize(t, loaded.Classification.Keys(KeyGrace), 1)
+	assertKeyListSize(t, loaded.Classification.Keys(KeyExpired), 1)
+	assertKeyListSize(t, loaded.DestroyKeys, 1)
+	for i, c := range loaded.Classification {
+		if c.Reason != plan.Classification[i].Reason || c.Remaining != plan.Classification[i].Remaining {
+			t.Errorf("Expected classification %+v, got %+v", plan.Classification[i], c)
+		}
+	}
+	if loaded.CreateKey != plan.CreateKey {
+		t.Errorf("Expected create key to be %t, got %t", plan.CreateKey, loaded.CreateKey)
+	}
+	if loaded.Policy.Name != "graceful-expiration" {
+		t.Errorf("Expected policy to be recorded, got %+v", loaded.Policy)
+	}
+	if !loaded.Created.Equal(plan.Created) {
+		t.Errorf("Expected created %s, got %s", plan.Created, loaded.Created)
+	}
+}
+
+func TestRebindAppliesToLiveKeys(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	expired := store.mockExpired(5)
+	loaded := roundTripPlan(t, planFor(t, store))
+
+	plan, err := loaded.Rebind(ctx, store)
+	assertNoError(t, err)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != expired {
+		t.Errorf("Expected plan to destroy the live expired key, got %+v", plan.DestroyKeys)
+	}
+
+	_, err = plan.Apply(ctx, store)
+	assertNoError(t, err)
+	store.assertCreatedKey(t)
+	store.assertKeyCountDeleted(t, 1)
+}
+
+func TestRebindEnforcesDestructiveLimit(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockExpired(15)
+	store.mockExpired(5)
+	planner, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithMaximumDestructiveOperations(2))
+	assertNoError(t, err)
+	plan, err := planner.Plan(ctx, store)
+	assertNoError(t, err)
+	loaded := roundTripPlan(t, plan)
+	if loaded.MaximumDestructiveOperations != 2 {
+		t.Fatalf("Expected the destructive limit to be persisted, got %d", loaded.MaximumDestructiveOperations)
+	}
+
+	//Parameters are informational, the persisted limit is enforced.
+	delete(loaded.Policy.Parameters, "maximum-destructive-operations")
+	loaded.MaximumDestructiveOperations = 1
+	if _, err := loaded.Rebind(ctx, store); err == nil {
+		t.Error("Expected a plan exceeding the destructive limit of its policy to be rejected")
+	}
+	store.assertKeyCountDeleted(t, 0)
+}
+
+func TestRemainingPlanRebindsAfterDisabling(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.snapshots = true
+	store.mockGoodKey()
+	store.mockExpired(5)
+	store.mockExpired(45)
+	planner, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithQuarantine(30*time.Second))
+	assertNoError(t, err)
+	plan, err := planner.Plan(ctx, store)
+	assertNoError(t, err)
+	assertKeyListSize(t, plan.DisableKeys, 1)
+	assertKeyListSize(t, plan.DestroyKeys, 1)
+
+	store.deleteErr = errors.New("delete failed")
+	var applyErr *ApplyError
+	if _, err := plan.Apply(ctx, store); !errors.As(err, &applyErr) {
+		t.Fatalf("Expected an ApplyError, got %v", err)
+	}
+	assertKeyListSize(t, applyErr.Disabled, 1)
+
+	store.deleteErr = nil
+	resumed, err := roundTripPlan(t, applyErr.Remaining).Rebind(ctx, store)
+	assertNoError(t, err)
+	assertEmptyKeyList(t, resumed.DisableKeys)
+	assertKeyListSize(t, resumed.DestroyKeys, 1)
+	if c, _ := resumed.Classification.Find(resumed.DestroyKeys[0]); c.State != KeyExpired {
+		t.Errorf("Expected the remaining key to still be expired, got %+v", c)
+	}
+	_, err = resumed.Apply(ctx, store)
+	assertNoError(t, err)
+	store.assertKeyCountDeleted(t, 1)
+}
+
+func TestRebindRejectsMissingKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockExpired(5)
+	loaded := roundTripPlan(t, planFor(t, store))
+
+	store.keys = store.keys[:0]
+	var stale *StalePlanError
+	if _, err := loaded.Rebind(ctx, store); !errors.As(err, &stale) || !errors.Is(err, ErrStalePlan) {
+		t.Errorf("Expected stale plan to be rejected, got %v", err)
+	}
+}
+
+func TestRebindRejectsAddedKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockInGrace()
+	loaded := roundTripPlan(t, planFor(t, store))
+
+	store.mockGoodKey()
+	var stale *StalePlanError
+	if _, err := loaded.Rebind(ctx, store); !errors.As(err, &stale) || !errors.Is(err, ErrStalePlan) {
+		t.Errorf("Expected stale plan to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package rotation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Planner decides the operations required to bring the keys of a KeyStore in line with a rotation strategy.
+type Planner interface {
+	Plan(ctx context.Context, store KeyStore) (*KeyRotationPlan, error)
+}
+
+//PlannerSettings are the common tunables provided to a registered strategy when building a Planner.  Strategies
+//ignore settings which are not meaningful to them.
+type PlannerSettings struct {
+	//MaximumAge is the age at which keys are considered expired.
+	MaximumAge time.Duration
+	//GraceAge is the age at which keys should no longer be preferred.
+	GraceAge time.Duration
+	//Clock is the source of the current time.  nil uses SystemClock.
+	Clock Clock
+	//Eviction selects grace keys to destroy when a store is at capacity.  nil uses the strategy's default.
+	Eviction EvictionPolicy
+	//Quarantine is how long keys are disabled before being destroyed.  Zero destroys keys immediately.
+	Quarantine time.Duration
+	//UsageWindow protects grace keys used within the window from destruction according to UsageGuard.  Zero disables.
+	UsageWindow time.Duration
+	//UsageGuard determines how recently used keys are protected.  Empty uses UsageGuardRefuse.
+	UsageGuard UsageGuard
+	//MinimumCreationInterval is the minimum age of the newest key before another is created.  Zero disables.
+	MinimumCreationInterval time.Duration
+	//MaximumDestructiveOperations caps the keys disabled or destroyed by a single plan.  Zero disables.  The cap applies
+	//to each target separately, so an Executor rotating many targets may exceed it in total.
+	MaximumDestructiveOperations int
+}
+
+//PlannerFactory builds a Planner for a strategy from the given settings.
+type PlannerFactory func(settings PlannerSettings) (Planner, error)
+
+var (
+	plannersLock sync.RWMutex
+	planners     = make(map[string]PlannerFactory)
+)
+
+//RegisterPlanner makes a strategy available by name through NewPlanner.  Registering the same name twice panics.
+func RegisterPlanner(name string, factory PlannerFactory) {
+	plannersLock.Lock()
+	defer plannersLock.Unlock()
+	if factory == nil {
+		panic("rotation: planner factory for " + name + " is nil")
+	}
+	if _, exists := planners[name]; exists {
+		panic("rotation: planner " + name + " registered twice")
+	}
+	planners[name] = factory
+}
+
+//NewPlanner builds a Planner using the strategy registered under the given name.
+func NewPlanner(name string, settings PlannerSettings) (Planner, error) {
+	plannersLock.RLock()
+	factory, ok := planners[name]
+	plannersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no such rotation strategy %q", name)
+	}
+	return factory(settings)
+}
+
+//PlannerNames lists the names of all registered strategies in sorted order.
+func PlannerNames() []string {
+	plannersLock.RLock()
+	defer plannersLock.RUnlock()
+	names := make([]string, 0, len(planners))
+	for name := range planners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterPlanner(GracefulExpirationStrategy, func(settings PlannerSettings) (Planner, error) {
+		var options []GracefulOption
+		if settings.Clock != nil {
+			options = append(options, WithClock(settings.Clock))
+		}
+		if settings.Eviction != nil {
+			options = append(options, WithEvictionPolicy(settings.Eviction))
+		}
+		if settings.Quarantine > 0 {
+			options = append(options, WithQuarantine(settings.Quarantine))
+		}
+		if settings.UsageWindow > 0 {
+			guard := settings.UsageGuard
+			if guard == "" {
+				guard = UsageGuardRefuse
+			}
+			options = append(options, WithUsageGuard(settings.UsageWindow, guard))
+		}
+		if settings.MinimumCreationInterval > 0 {
+			options = append(options, WithMinimumCreationInterval(settings.MinimumCreationInterval))
+		}
+		if settings.MaximumDestructiveOperations > 0 {
+			options = append(options, WithMaximumDestructiveOperations(settings.MaximumDestructiveOperations))
+		}
+		return NewGracefulExpiration(settings.MaximumAge, settings.GraceAge, options...)
+	})
+}
@@ -0,0 +1,80 @@
+package rotation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fixedPlanner struct {
+	settings PlannerSettings
+}
+
+func (f *fixedPlanner) Plan(ctx context.Context, store KeyStore) (*KeyRotationPlan, error) {
+	return &KeyRotationPlan{CreateKey: true, Policy: Policy{Name: "fixed"}}, nil
+}
+
+func init() {
+	RegisterPlanner("test-fixed", func(settings PlannerSettings) (Planner, error) {
+		return &fixedPlanner{settings: settings}, nil
+	})
+}
+
+func TestNewPlannerBuildsGracefulExpiration(t *testing.T) {
+	planner, err := NewPlanner(GracefulExpirationStrategy, PlannerSettings{
+		MaximumAge: 1 * time.Minute,
+		GraceAge:   30 * time.Second,
+		Eviction:   EvictNewest,
+	})
+	assertNoError(t, err)
+
+	graceful, ok := planner.(*GracefulExpiration)
+	if !ok {
+		t.Fatalf("Expected *GracefulExpiration, got %T", planner)
+	}
+	if graceful.evictionPolicy() != EvictNewest {
+		t.Errorf("Expected eviction setting to be applied, got %s", graceful.evictionPolicy().Name())
+	}
+}
+
+func TestNewPlannerUsesRegisteredStrategy(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	planner, err := NewPlanner("test-fixed", PlannerSettings{MaximumAge: time.Hour})
+	assertNoError(t, err)
+	plan, err := planner.Plan(ctx, newMock())
+	assertNoError(t, err)
+	plan.assertCreating(t)
+	if planner.(*fixedPlanner).settings.MaximumAge != time.Hour {
+		t.Error("Expected settings to be provided to the strategy")
+	}
+}
+
+func TestNewPlannerRejectsUnknownStrategy(t *testing.T) {
+	if _, err := NewPlanner("no-such-strategy", PlannerSettings{}); err == nil {
+		t.Error("Expected unknown strategy to be rejected")
+	}
+}
+
+func TestPlannerNamesIncludesBuiltIns(t *testing.T) {
+	names := PlannerNames()
+	found := false
+	for _, name := range names {
+		found = found || name == GracefulExpirationStrategy
+	}
+	if !found {
+		t.Errorf("Expected %q within %q", GracefulExpirationStrategy, names)
+	}
+}
+
+func TestRegisterPlannerTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected duplicate registration to panic")
+		}
+	}()
+	RegisterPlanner(GracefulExpirationStrategy, func(settings PlannerSettings) (Planner, error) {
+		return nil, nil
+	})
+}
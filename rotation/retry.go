@@ -0,0 +1,149 @@
+package rotation
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+//Backoff computes exponentially increasing waits between attempts of an operation.
+type Backoff struct {
+	//Initial is the wait after the first failed attempt, doubling after each subsequent failure.
+	Initial time.Duration
+	//Maximum caps the wait between attempts.
+	Maximum time.Duration
+	//Jitter is the fraction of each wait, between 0 and 1, which is randomized to spread out concurrent retries.
+	Jitter float64
+}
+
+//DefaultBackoff is suitable for retrying calls against remote APIs.
+var DefaultBackoff = Backoff{Initial: 250 * time.Millisecond, Maximum: 10 * time.Second, Jitter: 0.5}
+
+//Delay is the wait following the given failed attempt, counting from one.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < attempt && delay < b.Maximum; i++ {
+		delay *= 2
+	}
+	if delay > b.Maximum {
+		delay = b.Maximum
+	}
+	if b.Jitter > 0 {
+		delay -= time.Duration(b.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+//Wait blocks for the delay following the given failed attempt.  An error is returned without waiting if the context
+//would expire before the delay passes, or if the context is done while waiting.
+func (b Backoff) Wait(ctx context.Context, attempt int) error {
+	delay := b.Delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return context.DeadlineExceeded
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+//RetryOption configures a RetryingKeyStore.
+type RetryOption func(r *RetryingKeyStore)
+
+//WithAttempts sets the maximum number of attempts of each operation, including the first.
+func WithAttempts(attempts int) RetryOption {
+	return func(r *RetryingKeyStore) {
+		r.attempts = attempts
+	}
+}
+
+//WithBackoff sets the waits between attempts.
+func WithBackoff(backoff Backoff) RetryOption {
+	return func(r *RetryingKeyStore) {
+		r.backoff = backoff
+	}
+}
+
+//RetryingKeyStore retries operations of the wrapped store which fail with errors the binding considers transient.
+//Retrying CreateKey may produce an extra key should the store have completed a request reported as failed.
+type RetryingKeyStore struct {
+	KeyStoreDecorator
+	retryable func(err error) bool
+	attempts  int
+	backoff   Backoff
+}
+
+//NewRetryingKeyStore wraps the store, retrying each of its operations.  retryable classifies errors as transient; by
+//default each operation is attempted up to 5 times using DefaultBackoff.
+func NewRetryingKeyStore(store KeyStore, retryable func(err error) bool, options ...RetryOption) KeyStore {
+	retrying := &RetryingKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		retryable:         retryable,
+		attempts:          5,
+		backoff:           DefaultBackoff,
+	}
+	for _, option := range options {
+		option(retrying)
+	}
+	return PreserveCapabilities(retrying)
+}
+
+//retry performs the operation until it succeeds, fails with an error which is not transient, or the attempts are
+//exhausted.  The last error of the operation is returned.
+func (r *RetryingKeyStore) retry(ctx context.Context, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= r.attempts || !r.retryable(err) {
+			return err
+		}
+		if r.backoff.Wait(ctx, attempt) != nil {
+			return err
+		}
+	}
+}
+
+//CreateKey never retries once a key has been returned, even with an error, as the key already exists.
+func (r *RetryingKeyStore) CreateKey(ctx context.Context) (key Key, err error) {
+	var createErr error
+	err = r.retry(ctx, func() error {
+		key, createErr = r.Wrapped.CreateKey(ctx)
+		if key != nil {
+			return nil
+		}
+		return createErr
+	})
+	if key != nil {
+		return key, createErr
+	}
+	return nil, err
+}
+
+func (r *RetryingKeyStore) DeleteKey(ctx context.Context, key Key) error {
+	return r.retry(ctx, func() error {
+		return r.Wrapped.DeleteKey(ctx, key)
+	})
+}
+
+func (r *RetryingKeyStore) ListKeys(ctx context.Context) (keys KeyList, err error) {
+	err = r.retry(ctx, func() error {
+		keys, err = r.Wrapped.ListKeys(ctx)
+		return err
+	})
+	return keys, err
+}
+
+func (r *RetryingKeyStore) DisableKey(ctx context.Context, key Key) error {
+	return r.retry(ctx, func() error {
+		return disableWith(ctx, r.Wrapped, key)
+	})
+}
+
+func (r *RetryingKeyStore) EnableKey(ctx context.Context, key Key) error {
+	return r.retry(ctx, func() error {
+		return enableWith(ctx, r.Wrapped, key)
+	})
+}
@@ -0,0 +1,168 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("throttled")
+
+//flakyStore fails the first operations with the given error before delegating to the mock.
+type flakyStore struct {
+	*mockKeyStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyStore) fail() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyStore) CreateKey(ctx context.Context) (Key, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return f.mockKeyStore.CreateKey(ctx)
+}
+
+func (f *flakyStore) ListKeys(ctx context.Context) (KeyList, error) {
+	if err := f.fail(); err != nil {
+		return nil, err
+	}
+	return f.mockKeyStore.ListKeys(ctx)
+}
+
+func (f *flakyStore) DisableKey(ctx context.Context, key Key) error {
+	if err := f.fail(); err != nil {
+		return err
+	}
+	return f.mockKeyStore.DisableKey(ctx, key)
+}
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+var testBackoff = Backoff{Initial: time.Millisecond, Maximum: 2 * time.Millisecond}
+
+func TestRetriesTransientErrors(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	flaky := &flakyStore{mockKeyStore: newMock(), failures: 2, err: errTransient}
+	store := NewRetryingKeyStore(flaky, isTransient, WithBackoff(testBackoff))
+	if _, err := store.CreateKey(ctx); err != nil {
+		t.Fatalf("Expected creation to succeed after retrying, got %s", err.Error())
+	}
+	if flaky.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", flaky.calls)
+	}
+}
+
+func TestDoesNotRetryPermanentErrors(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	permanent := errors.New("access denied")
+	flaky := &flakyStore{mockKeyStore: newMock(), failures: 2, err: permanent}
+	store := NewRetryingKeyStore(flaky, isTransient, WithBackoff(testBackoff))
+	if _, err := store.ListKeys(ctx); !errors.Is(err, permanent) {
+		t.Fatalf("Expected the permanent error, got %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", flaky.calls)
+	}
+}
+
+func TestRetriesLimitedByAttempts(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	flaky := &flakyStore{mockKeyStore: newMock(), failures: 10, err: errTransient}
+	store := NewRetryingKeyStore(flaky, isTransient, WithBackoff(testBackoff), WithAttempts(3))
+	if _, err := store.ListKeys(ctx); !errors.Is(err, errTransient) {
+		t.Fatalf("Expected the last error, got %v", err)
+	}
+	if flaky.calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", flaky.calls)
+	}
+}
+
+func TestRetriesStopAtContextDeadline(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer done()
+
+	flaky := &flakyStore{mockKeyStore: newMock(), failures: 10, err: errTransient}
+	store := NewRetryingKeyStore(flaky, isTransient, WithBackoff(Backoff{Initial: time.Minute, Maximum: time.Minute}))
+	if _, err := store.ListKeys(ctx); !errors.Is(err, errTransient) {
+		t.Fatalf("Expected the last error, got %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("Expected no retry once the deadline would pass, got %d attempts", flaky.calls)
+	}
+}
+
+func TestRetryingStorePreservesDisabling(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	key := mock.mockGoodKey()
+	flaky := &flakyStore{mockKeyStore: mock, failures: 1, err: errTransient}
+	store := NewRetryingKeyStore(flaky, isTransient, WithBackoff(testBackoff))
+	disabler, ok := store.(DisablingKeyStore)
+	if !ok {
+		t.Fatal("Expected the retrying store to support disabling keys")
+	}
+	assertNoError(t, disabler.DisableKey(ctx, key))
+	if key.Active() {
+		t.Error("Expected key to be disabled")
+	}
+}
+
+func TestBackoffDelayGrowsToMaximum(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Maximum: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, e := range expected {
+		if delay := backoff.Delay(i + 1); delay != e {
+			t.Errorf("Expected delay after attempt %d to be %s, got %s", i+1, e, delay)
+		}
+	}
+
+	backoff.Jitter = 0.5
+	for attempt := 1; attempt < 10; attempt++ {
+		if delay := backoff.Delay(attempt); delay < 500*time.Millisecond || delay > 5*time.Second {
+			t.Errorf("Expected jittered delay within bounds, got %s", delay)
+		}
+	}
+}
+
+//createdWithErrorStore creates keys while reporting a transient error, such as a failure to record them.
+type createdWithErrorStore struct {
+	*mockKeyStore
+}
+
+func (c *createdWithErrorStore) CreateKey(ctx context.Context) (Key, error) {
+	key, _ := c.mockKeyStore.CreateKey(ctx)
+	return key, errTransient
+}
+
+func TestRetryNeverRecreatesReturnedKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	store := NewRetryingKeyStore(&createdWithErrorStore{mockKeyStore: mock}, isTransient, WithBackoff(testBackoff))
+	key, err := store.CreateKey(ctx)
+	if key == nil || !errors.Is(err, errTransient) {
+		t.Fatalf("Expected the key to be returned with the error, got %+v and %v", key, err)
+	}
+	mock.assertEvents(t, "create mock-1")
+}
@@ -0,0 +1,70 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+//EnablingKeyStore is an optional capability of a KeyStore able to reactivate previously disabled keys.
+type EnablingKeyStore interface {
+	KeyStore
+
+	//EnableKey reactivates a key previously disabled through DisablingKeyStore.DisableKey.
+	EnableKey(ctx context.Context, key Key) error
+}
+
+//RollbackResult describes the changes made by Rollback.
+type RollbackResult struct {
+	//Enabled is the previously disabled key which has been reactivated.
+	Enabled Key
+	//Disabled is the newest key which has been deactivated.  nil if deactivation did not occur.
+	Disabled Key
+}
+
+//Rollback reverts to the previous key of a store by reactivating the most recently created disabled key and then
+//deactivating the newest active key.  Rollback refuses to act when no disabled key older than the newest active key
+//remains, such as when the previous key has already been deleted.  The store must support both enabling and disabling
+//keys.
+//
+//Rollback does not pin the reactivated key.  When it has exceeded the maximum age of the rotation policy the next
+//rotation creates a new key and quarantines the reactivated key again, destroying the deactivated key if a slot is
+//required.  Scheduled rotations should be paused until the problem with the newer key is resolved.
+func Rollback(ctx context.Context, store KeyStore) (*RollbackResult, error) {
+	enabler, ok := store.(EnablingKeyStore)
+	if !ok {
+		return nil, errors.New("store does not support enabling keys")
+	}
+	disabler, ok := store.(DisablingKeyStore)
+	if !ok {
+		return nil, errors.New("store does not support disabling keys")
+	}
+
+	keys, err := store.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	newest, ok := keys.Filter(IsActive).Newest()
+	if !ok {
+		return nil, errors.New("no active key to roll back from")
+	}
+
+	previous, ok := keys.Filter(func(k Key) bool {
+		return !IsActive(k) && k.Created().Before(newest.Created())
+	}).Newest()
+	if !ok {
+		return nil, errors.New("no previously disabled key remains to roll back to, it may have already been deleted")
+	}
+
+	result := &RollbackResult{}
+	if err := enabler.EnableKey(ctx, previous); err != nil {
+		return nil, fmt.Errorf("reactivating previous key: %w", err)
+	}
+	result.Enabled = previous
+	if err := disabler.DisableKey(ctx, newest); err != nil {
+		return result, fmt.Errorf("previous key reactivated but deactivating newest key failed: %w", err)
+	}
+	result.Disabled = newest
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package rotation
+
+import "testing"
+
+func TestRollbackReactivatesPreviousKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockExpired(50).inactive = true
+	previous := store.mockExpired(5)
+	previous.inactive = true
+	newest := store.mockGoodKey()
+
+	result, err := Rollback(ctx, store)
+	assertNoError(t, err)
+	if result.Enabled != previous || result.Disabled != newest {
+		t.Errorf("Expected to enable %s and disable %s, got %+v", previous.id, newest.id, result)
+	}
+	store.assertEvents(t, "enable mock-2", "disable mock-3")
+}
+
+func TestRollbackRefusesWithoutPreviousKey(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockInGrace()
+	store.mockGoodKey()
+
+	if _, err := Rollback(ctx, store); err == nil {
+		t.Error("Expected rollback to be refused")
+	}
+	store.assertEvents(t)
+}
+
+func TestRollbackIgnoresDisabledKeysNewerThanActive(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	store.mockInGrace()
+	store.mockGoodKey().inactive = true
+
+	if _, err := Rollback(ctx, store); err == nil {
+		t.Error("Expected rollback to be refused")
+	}
+}
+
+func TestRollbackRequiresCapabilities(t *testing.T) {
+	ctx, done := testContext(t)
+	defer done()
+
+	store := newMock()
+	if _, err := Rollback(ctx, &KeyStoreDecorator{Wrapped: store}); err == nil {
+		t.Error("Expected rollback to require enabling and disabling keys")
+	}
+}
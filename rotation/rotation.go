@@ -4,29 +4,375 @@ package rotation
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 )
 
+//KeyState is the life cycle phase a key has been classified into by a rotation policy.
+type KeyState string
+
+const (
+	//KeyValid keys are younger than the start of the grace period and should be preferred by clients.
+	KeyValid KeyState = "valid"
+	//KeyGrace keys are past their prime but remain usable while clients transition to a valid key.
+	KeyGrace KeyState = "grace"
+	//KeyDisabled keys are quarantined: inactive but retained so the change may be reversed until destroyed.
+	KeyDisabled KeyState = "disabled"
+	//KeyExpired keys are past their maximum age and are to be destroyed.
+	KeyExpired KeyState = "expired"
+)
+
+//KeyClassification is the state of a single key as determined by a rotation policy at a point in time.
+type KeyClassification struct {
+	Key Key
+	//State is the life cycle phase of the key.
+	State KeyState
+	//Age is how long ago the key was created.
+	Age time.Duration
+	//Remaining is the time until the key transitions into the next state.  Zero for keys which have expired.
+	Remaining time.Duration
+	//Reason is a human readable explanation for the classification.
+	Reason string
+}
+
+//Classification is the state of all keys within a store.
+type Classification []KeyClassification
+
+//Keys produces the keys within the given state, preserving order.
+func (c Classification) Keys(state KeyState) KeyList {
+	out := make(KeyList, 0)
+	for _, k := range c {
+		if k.State == state {
+			out = append(out, k.Key)
+		}
+	}
+	return out
+}
+
+//InState produces the classification of only the keys within the given state, preserving order.
+func (c Classification) InState(state KeyState) Classification {
+	out := make(Classification, 0)
+	for _, k := range c {
+		if k.State == state {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+//Partition groups the keys by state, preserving order within each state.
+func (c Classification) Partition() map[KeyState]KeyList {
+	out := make(map[KeyState]KeyList)
+	for _, k := range c {
+		out[k.State] = append(out[k.State], k.Key)
+	}
+	return out
+}
+
+//SortByCreated produces a copy of the classification ordered from the oldest key to the newest, in the same order as
+//KeyList.SortByCreated.
+func (c Classification) SortByCreated() Classification {
+	sorted := slices.Clone(c)
+	slices.SortStableFunc(sorted, func(a, b KeyClassification) int {
+		return compareCreated(a.Key, b.Key)
+	})
+	return sorted
+}
+
+//Find locates the classification for the given key.
+func (c Classification) Find(key Key) (KeyClassification, bool) {
+	for _, k := range c {
+		if k.Key == key {
+			return k, true
+		}
+	}
+	return KeyClassification{}, false
+}
+
+//Without produces the classification excluding the given keys.
+func (c Classification) Without(keys KeyList) Classification {
+	out := make(Classification, 0, len(c))
+	for _, k := range c {
+		if !keys.Contains(k.Key) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+//Classifier is implemented by rotation policies able to report the state of each key within a store.
+type Classifier interface {
+	Classify(ctx context.Context, store KeyStore) (Classification, error)
+}
+
+//Policy describes the rotation policy and parameters which produced a plan.  Parameters are rendered in a human
+//readable form and are informational only.
+type Policy struct {
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
 //KeyRotationPlan is the instructions to realize a specific rotation strategy against a KeyStore.
 type KeyRotationPlan struct {
-	CreateKey   bool
+	CreateKey bool
+	//CreateDeferredUntil is the earliest time a new key will be created when creation has been postponed by a minimum
+	//creation interval.  Zero when creation was not deferred.
+	CreateDeferredUntil time.Time
+	//DisableKeys are to be deactivated but retained within the store.  Requires a DisablingKeyStore.
+	DisableKeys KeyList
 	DestroyKeys KeyList
-	goodKeys    KeyList
+	//Target optionally names the KeyStore the plan was produced against.  Not interpreted by the rotation package.
+	Target string
+	//Policy is the rotation policy used to produce the plan.
+	Policy Policy
+	//Created is the time the plan was produced.
+	Created time.Time
+	//Classification is the state of every key within the store at the time of planning.
+	Classification Classification
+	//Eviction records the grace key selected for destruction to free a slot, if any.
+	Eviction *Eviction
+	//Warnings are concerns noted while planning which do not prevent the plan from being applied.
+	Warnings []string
+	//MaximumDestructiveOperations caps the keys disabled and destroyed by the plan combined, enforced again while
+	//rebinding and applying.  Zero imposes no limit.
+	MaximumDestructiveOperations int
+	//Hooks are invoked around each operation while applying the plan.  Hooks are not persisted.
+	Hooks Hooks
+}
+
+//HasChanges determines if applying the plan would modify the store.
+func (plan *KeyRotationPlan) HasChanges() bool {
+	return plan.CreateKey || len(plan.DisableKeys) > 0 || len(plan.DestroyKeys) > 0
+}
+
+//checkDestructiveLimit fails when the plan disables or destroys more keys than MaximumDestructiveOperations permits.
+//Checked while rebinding and applying as well as planning, so plans loaded from a file or altered after planning
+//remain capped.
+func (plan *KeyRotationPlan) checkDestructiveLimit() error {
+	if plan.MaximumDestructiveOperations < 0 {
+		return fmt.Errorf("maximum destructive operations (%d) must not be negative", plan.MaximumDestructiveOperations)
+	}
+	return destructiveLimitError(len(plan.DisableKeys)+len(plan.DestroyKeys), plan.MaximumDestructiveOperations)
+}
+
+//destructiveLimitError fails when the number of keys disabled or destroyed exceeds a positive limit.
+func destructiveLimitError(destructive int, limit int) error {
+	if limit > 0 && destructive > limit {
+		return fmt.Errorf("plan would disable or destroy %d keys exceeding the limit of %d", destructive, limit)
+	}
+	return nil
 }
 
 //Apply performs the desired operations against a given store.  If successful a KeyList of healthy keys are returned.
+//
+//Replacement keys are created before any keys are disabled or destroyed whenever the store has an available slot.
+//When the store is at capacity only as many keys as required to free a slot are destroyed ahead of creation.  Keys
+//vetoed by a hook are retained.  Nothing is changed when the plan exceeds its MaximumDestructiveOperations.  On failure an *ApplyError describes the changes made and the operations left undone.
 func (plan *KeyRotationPlan) Apply(ctx context.Context, store KeyStore) (KeyList, error) {
-	knownKeys := plan.goodKeys
-	for _, k := range plan.DestroyKeys {
-		if err := store.DeleteKey(ctx, k); err != nil {
-			return nil, err
+	return plan.applyTraced(ctx, store, &applyProgress{})
+}
+
+//applyTraced applies the plan within a span, recording the changes made within progress.
+func (plan *KeyRotationPlan) applyTraced(ctx context.Context, store KeyStore, progress *applyProgress) (keys KeyList, err error) {
+	ctx, span := tracer().Start(ctx, "KeyRotationPlan.Apply",
+		attributeTarget.String(plan.Target),
+		attributePolicy.String(plan.Policy.Name),
+	)
+	defer func() { endSpan(span, err) }()
+	return plan.apply(ctx, store, progress)
+}
+
+//apply performs the operations of the plan in order, recording the changes made within progress.
+func (plan *KeyRotationPlan) apply(ctx context.Context, store KeyStore, progress *applyProgress) (KeyList, error) {
+	if err := plan.checkDestructiveLimit(); err != nil {
+		return nil, err
+	}
+	var disabler DisablingKeyStore
+	if len(plan.DisableKeys) > 0 {
+		var ok bool
+		if disabler, ok = store.(DisablingKeyStore); !ok {
+			return nil, errors.New("plan disables keys but the store does not support disabling keys")
+		}
+	}
+
+	knownKeys := plan.Classification.Keys(KeyValid)
+	progress.pendingDisable = append(KeyList{}, plan.DisableKeys...)
+	progress.pendingDestroy = append(KeyList{}, plan.DestroyKeys...)
+	failed := func(err error) error {
+		return &ApplyError{
+			Deleted:   progress.deleted,
+			Disabled:  progress.disabled,
+			Created:   progress.created,
+			Discarded: progress.discarded,
+			Vetoed:    progress.vetoed,
+			Remaining: plan.remaining(progress),
+			Err:       err,
 		}
 	}
+	destroyNext := func() (bool, error) {
+		next := progress.pendingDestroy[0]
+		if err := plan.Hooks.BeforeDelete(ctx, plan, next); errors.Is(err, ErrVetoed) {
+			progress.vetoed = append(progress.vetoed, next)
+			progress.pendingDestroy = progress.pendingDestroy[1:]
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if err := store.DeleteKey(ctx, next); err != nil {
+			return false, err
+		}
+		progress.deleted = append(progress.deleted, next)
+		progress.pendingDestroy = progress.pendingDestroy[1:]
+		return true, nil
+	}
+
 	if plan.CreateKey {
+		freeSlots := store.MaximumKeys() - plan.keyCount()
+		for freeSlots < 1 && len(progress.pendingDestroy) > 0 {
+			freed, err := destroyNext()
+			if err != nil {
+				return nil, failed(err)
+			}
+			if freed {
+				freeSlots++
+			}
+		}
+		if freeSlots < 1 && len(progress.vetoed) > 0 {
+			return nil, failed(&CapacityError{Maximum: store.MaximumKeys(), Reason: "deletion freeing a slot", Err: ErrVetoed})
+		}
+		if err := plan.Hooks.BeforeCreate(ctx, plan); err != nil {
+			return nil, failed(err)
+		}
 		key, err := store.CreateKey(ctx)
 		if err != nil {
-			return nil, err
+			//A key returned with an error still exists and is reported rather than orphaned.
+			progress.created = key
+			return nil, failed(err)
 		}
+		progress.created = key
 		knownKeys = append(knownKeys, key)
+		if err := plan.Hooks.AfterCreate(ctx, plan, key); err != nil {
+			return nil, failed(discard(ctx, store, progress, err))
+		}
+	}
+	for len(progress.pendingDisable) > 0 {
+		next := progress.pendingDisable[0]
+		if err := disabler.DisableKey(ctx, next); err != nil {
+			return nil, failed(err)
+		}
+		progress.disabled = append(progress.disabled, next)
+		progress.pendingDisable = progress.pendingDisable[1:]
+	}
+	for len(progress.pendingDestroy) > 0 {
+		if _, err := destroyNext(); err != nil {
+			return nil, failed(err)
+		}
+	}
+	if err := plan.Hooks.AfterApply(ctx, plan, knownKeys); err != nil {
+		return nil, failed(err)
 	}
 	return knownKeys, nil
 }
+
+//discard destroys the key created by the progress after a hook rejected it, such as when the key could not be
+//verified or delivered, so the next rotation creates a replacement rather than treating the key as valid.  The key
+//remains reported as created if it can not be destroyed.
+func discard(ctx context.Context, store KeyStore, progress *applyProgress, hookErr error) error {
+	key := progress.created
+	if err := store.DeleteKey(context.WithoutCancel(ctx), key); err != nil {
+		return fmt.Errorf("%w (additionally failed discarding new key %s: %s)", hookErr, describeKey(key), err.Error())
+	}
+	progress.created = nil
+	progress.discarded = key
+	return fmt.Errorf("new key %s discarded: %w", describeKey(key), hookErr)
+}
+
+//applyProgress tracks the operations performed while applying a plan.
+type applyProgress struct {
+	deleted        KeyList
+	disabled       KeyList
+	created        Key
+	discarded      Key
+	vetoed         KeyList
+	pendingDisable KeyList
+	pendingDestroy KeyList
+}
+
+//keyCount is the number of keys within the store at the time of planning.
+func (plan *KeyRotationPlan) keyCount() int {
+	count := len(plan.Classification)
+	for _, k := range plan.DestroyKeys {
+		if _, ok := plan.Classification.Find(k); !ok {
+			count++
+		}
+	}
+	return count
+}
+
+//remaining produces a plan of the operations left undone after the given progress was made against the store.
+func (plan *KeyRotationPlan) remaining(progress *applyProgress) *KeyRotationPlan {
+	result := &KeyRotationPlan{
+		CreateKey:           plan.CreateKey && progress.created == nil,
+		CreateDeferredUntil: plan.CreateDeferredUntil,
+		DisableKeys:         append(KeyList{}, progress.pendingDisable...),
+		DestroyKeys:         append(KeyList{}, progress.pendingDestroy...),
+		Target:              plan.Target,
+		Policy:              plan.Policy,
+		Created:             plan.Created,
+		Classification:      plan.Classification.Without(progress.deleted),
+		Warnings:            plan.Warnings,
+		Hooks:               plan.Hooks,
+
+		MaximumDestructiveOperations: plan.MaximumDestructiveOperations,
+	}
+	if plan.Eviction != nil && progress.pendingDestroy.Contains(plan.Eviction.Key) {
+		result.Eviction = plan.Eviction
+	}
+	for i, c := range result.Classification {
+		if progress.disabled.Contains(c.Key) {
+			result.Classification[i].State = KeyDisabled
+			result.Classification[i].Reason = "disabled while applying plan"
+		}
+	}
+	if progress.created != nil {
+		result.Classification = append(result.Classification, KeyClassification{
+			Key:    progress.created,
+			State:  KeyValid,
+			Reason: "created while applying plan",
+		})
+	}
+	return result
+}
+
+//ApplyError reports a plan which was only partially applied.  Deleted and Created describe the changes made to the
+//store before the failure while Remaining is a plan of the operations left undone, suitable for resuming.
+type ApplyError struct {
+	//Deleted are the keys destroyed before the failure.
+	Deleted KeyList
+	//Disabled are the keys deactivated before the failure.
+	Disabled KeyList
+	//Created is the key created before the failure, if any.  The key exists within the store.
+	Created Key
+	//Discarded is the key created then destroyed again because a hook rejected it, if any.
+	Discarded Key
+	//Vetoed are the keys a hook prevented from being destroyed.
+	Vetoed KeyList
+	//Remaining is the plan of operations which were not performed.
+	Remaining *KeyRotationPlan
+	//Err is the underlying cause of the failure.
+	Err error
+}
+
+func (a *ApplyError) Error() string {
+	created := 0
+	if a.Created != nil {
+		created = 1
+	}
+	return fmt.Sprintf("plan partially applied (%d deleted, %d disabled, %d created): %s", len(a.Deleted), len(a.Disabled), created, a.Err.Error())
+}
+
+func (a *ApplyError) Unwrap() error {
+	return a.Err
+}
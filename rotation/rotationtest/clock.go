@@ -0,0 +1,38 @@
+package rotationtest
+
+import (
+	"sync"
+	"time"
+)
+
+//Clock is a simulated rotation.Clock which only moves when told to, allowing tests to step through the life cycle of
+//keys without waiting.  Safe for concurrent use.
+type Clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+//NewClock produces a Clock reporting the given instant until advanced.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+//Advance moves the clock forward by the given duration.
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+//Set moves the clock to the given instant.
+func (c *Clock) Set(at time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = at
+}
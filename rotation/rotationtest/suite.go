@@ -0,0 +1,297 @@
+//Package rotationtest provides utilities for testing KeyStore implementations, including a conformance suite any
+//binding may run from its own tests.
+package rotationtest
+
+import (
+	"context"
+	"errors"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"testing"
+	"time"
+)
+
+//Epoch is the instant simulated clocks start at within the conformance suite.
+var Epoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+//StoreFactory produces an empty KeyStore for a single test.  Keys created by the store should report the time given
+//by clock as their creation time, allowing the suite to simulate the passage of time; stores unable to do so, such as
+//those backed by a real system, run the suite with WithRealTime.  Stores must permit at least one key.
+type StoreFactory func(t *testing.T, clock rotation.Clock) rotation.KeyStore
+
+//Option adjusts the conformance suite to the limitations of a store.
+type Option func(*options)
+
+type options struct {
+	realTime bool
+}
+
+//WithRealTime declares the store stamps keys with its own time, such as the time reported by a remote system, rather
+//than the clock given to the factory.  Checks requiring simulated time are skipped.
+func WithRealTime() Option {
+	return func(o *options) {
+		o.realTime = true
+	}
+}
+
+//Run verifies the store produced by the factory conforms to the expectations of the rotation package, with each
+//expectation checked as a subtest against a fresh store:
+//
+//   - created keys are listed until deleted
+//   - creating more than MaximumKeys keys fails with rotation.ErrNoCapacity
+//   - deleting a key no longer within the store fails without affecting other keys
+//   - operations fail without changing keys once the context has been cancelled
+//   - disabled keys remain listed as inactive until enabled, for stores supporting them
+//   - keys are created at the time given by the clock, unless WithRealTime
+//   - GracefulExpiration plans applied as time passes create and retire keys, unless WithRealTime
+func Run(t *testing.T, newStore StoreFactory, opts ...Option) {
+	config := &options{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	suite := []struct {
+		name string
+		test func(t *testing.T, clock *Clock, store rotation.KeyStore)
+		//simulated tests require keys to be created at the time given by the clock.
+		simulated bool
+	}{
+		{"RoundTrip", testRoundTrip, false},
+		{"MaximumKeys", testMaximumKeys, false},
+		{"DeleteUnknownKey", testDeleteUnknownKey, false},
+		{"CancelledContext", testCancelledContext, false},
+		{"DisableAndEnable", testDisableAndEnable, false},
+		{"CreatedAtClock", testCreatedAtClock, true},
+		{"PlanAndApply", testPlanAndApply, true},
+	}
+	for _, s := range suite {
+		t.Run(s.name, func(t *testing.T) {
+			if s.simulated && config.realTime {
+				t.Skip("store does not create keys at the simulated time")
+			}
+			clock := NewClock(Epoch)
+			s.test(t, clock, newStore(t, clock))
+		})
+	}
+}
+
+func testRoundTrip(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	ctx := context.Background()
+	requireKeys(t, ctx, store)
+
+	key, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+	if key.Created().Equal(rotation.InvalidTime()) {
+		t.Error("expected new key to have a valid creation time")
+	}
+	if !rotation.IsActive(key) {
+		t.Error("expected new key to be active")
+	}
+	listed := requireKeys(t, ctx, store, key)
+	if !listed[0].Created().Equal(key.Created()) {
+		t.Errorf("expected listed key created at %s, got %s", key.Created(), listed[0].Created())
+	}
+
+	if err := store.DeleteKey(ctx, key); err != nil {
+		t.Fatalf("deleting key: %s", err)
+	}
+	requireKeys(t, ctx, store)
+}
+
+func testCreatedAtClock(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	ctx := context.Background()
+	first, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+	if !first.Created().Equal(clock.Now()) {
+		t.Errorf("expected key created at %s, got %s", clock.Now(), first.Created())
+	}
+	if err := store.DeleteKey(ctx, first); err != nil {
+		t.Fatalf("deleting key: %s", err)
+	}
+
+	clock.Advance(time.Hour)
+	second, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+	if !second.Created().Equal(clock.Now()) {
+		t.Errorf("expected key created at %s once the clock advanced, got %s", clock.Now(), second.Created())
+	}
+}
+
+func testMaximumKeys(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	ctx := context.Background()
+	maximum := store.MaximumKeys()
+	if maximum < 1 {
+		t.Fatalf("expected store to permit at least one key, permits %d", maximum)
+	}
+
+	created := make(rotation.KeyList, maximum)
+	for i := range created {
+		clock.Advance(time.Minute)
+		key, err := store.CreateKey(ctx)
+		if err != nil {
+			t.Fatalf("creating key %d of %d: %s", i+1, maximum, err)
+		}
+		created[i] = key
+	}
+	if key, err := store.CreateKey(ctx); !errors.Is(err, rotation.ErrNoCapacity) {
+		t.Errorf("expected creating key beyond maximum of %d to fail for lack of capacity, got %+v and %v", maximum, key, err)
+	}
+	requireKeys(t, ctx, store, created...)
+}
+
+func testDeleteUnknownKey(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	ctx := context.Background()
+	deleted, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+	if err := store.DeleteKey(ctx, deleted); err != nil {
+		t.Fatalf("deleting key: %s", err)
+	}
+	kept, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+
+	if err := store.DeleteKey(ctx, deleted); err == nil {
+		t.Error("expected deleting a key no longer within the store to fail")
+	}
+	requireKeys(t, ctx, store, kept)
+}
+
+func testCancelledContext(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	key, err := store.CreateKey(context.Background())
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if created, err := store.CreateKey(ctx); err == nil {
+		t.Errorf("expected creating a key to fail once cancelled, created %+v", created)
+	}
+	if err := store.DeleteKey(ctx, key); err == nil {
+		t.Error("expected deleting a key to fail once cancelled")
+	}
+	if keys, err := store.ListKeys(ctx); err == nil {
+		t.Errorf("expected listing keys to fail once cancelled, listed %d keys", len(keys))
+	}
+	requireKeys(t, context.Background(), store, key)
+}
+
+func testDisableAndEnable(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	disabling, canDisable := store.(rotation.DisablingKeyStore)
+	enabling, canEnable := store.(rotation.EnablingKeyStore)
+	if !canDisable && !canEnable {
+		t.Skip("store does not support disabling or enabling keys")
+	}
+	ctx := context.Background()
+	key, err := store.CreateKey(ctx)
+	if err != nil {
+		t.Fatalf("creating key: %s", err)
+	}
+
+	if canDisable {
+		if err := disabling.DisableKey(ctx, key); err != nil {
+			t.Fatalf("disabling key: %s", err)
+		}
+		if listed := requireKeys(t, ctx, store, key); rotation.IsActive(listed[0]) {
+			t.Error("expected disabled key to be listed as inactive")
+		}
+	}
+	if canEnable {
+		if err := enabling.EnableKey(ctx, key); err != nil {
+			t.Fatalf("enabling key: %s", err)
+		}
+		if listed := requireKeys(t, ctx, store, key); !rotation.IsActive(listed[0]) {
+			t.Error("expected enabled key to be listed as active")
+		}
+	}
+}
+
+func testPlanAndApply(t *testing.T, clock *Clock, store rotation.KeyStore) {
+	ctx := context.Background()
+	policy, err := rotation.NewGracefulExpiration(2*time.Hour, time.Hour, rotation.WithClock(clock))
+	if err != nil {
+		t.Fatalf("creating policy: %s", err)
+	}
+	apply := func(step string) rotation.KeyList {
+		t.Helper()
+		plan, err := policy.Plan(ctx, store)
+		if err != nil {
+			t.Fatalf("planning %s: %s", step, err)
+		}
+		keys, err := plan.Apply(ctx, store)
+		if err != nil {
+			t.Fatalf("applying %s: %s", step, err)
+		}
+		return keys
+	}
+
+	first := apply("empty store")
+	if len(first) != 1 || !first[0].Created().Equal(clock.Now()) {
+		t.Fatalf("expected a single key created at %s for an empty store, got %+v", clock.Now(), first)
+	}
+	requireKeys(t, ctx, store, first...)
+
+	clock.Advance(90 * time.Minute)
+	second := apply("key in grace")
+	if len(second) != 1 || !second[0].Created().Equal(clock.Now()) {
+		t.Fatalf("expected a new key created at %s once the first entered grace, got %+v", clock.Now(), second)
+	}
+	if store.MaximumKeys() > 1 {
+		requireKeys(t, ctx, store, first[0], second[0])
+	} else {
+		requireKeys(t, ctx, store, second[0])
+	}
+
+	clock.Advance(time.Hour)
+	third := apply("key expired")
+	if len(third) != 1 || !third[0].Created().Equal(second[0].Created()) {
+		t.Fatalf("expected the second key to remain valid, got %+v", third)
+	}
+	requireKeys(t, ctx, store, second[0])
+}
+
+//requireKeys fails the test unless the store lists exactly the expected keys, matched by ID for identifiable keys and
+//by creation time otherwise.  The listed keys are returned in the expected order.
+func requireKeys(t *testing.T, ctx context.Context, store rotation.KeyStore, expected ...rotation.Key) rotation.KeyList {
+	t.Helper()
+	listed, err := store.ListKeys(ctx)
+	if err != nil {
+		t.Fatalf("listing keys: %s", err)
+	}
+	if len(listed) != len(expected) {
+		t.Fatalf("expected %d keys, store listed %d: %+v", len(expected), len(listed), listed)
+	}
+
+	out := make(rotation.KeyList, len(expected))
+	remaining := listed
+	for i, e := range expected {
+		found, ok := findKey(remaining, e)
+		if !ok {
+			t.Fatalf("expected store to list %+v, listed %+v", e, listed)
+		}
+		out[i] = found
+		remaining = remaining.Without(rotation.KeyList{found})
+	}
+	return out
+}
+
+//findKey locates the key within the list matching the expected key.
+func findKey(keys rotation.KeyList, expected rotation.Key) (rotation.Key, bool) {
+	if id, ok := rotation.IDOf(expected); ok {
+		return keys.ByID(id)
+	}
+	matching := keys.Filter(func(k rotation.Key) bool {
+		return k.Created().Equal(expected.Created())
+	})
+	if len(matching) == 0 {
+		return nil, false
+	}
+	return matching[0], true
+}
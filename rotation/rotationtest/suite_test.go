@@ -0,0 +1,108 @@
+package rotationtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/truewhitespace/key-rotation/rotation"
+	"testing"
+	"time"
+)
+
+//sliceKey is a key of sliceStore.
+type sliceKey struct {
+	id      string
+	created time.Time
+	active  bool
+}
+
+func (s *sliceKey) Created() time.Time {
+	return s.created
+}
+
+func (s *sliceKey) KeyID() string {
+	return s.id
+}
+
+func (s *sliceKey) Active() bool {
+	return s.active
+}
+
+//sliceStore is a minimal conforming store.
+type sliceStore struct {
+	clock   rotation.Clock
+	keys    rotation.KeyList
+	issued  int
+	maximum int
+}
+
+func (s *sliceStore) CreateKey(ctx context.Context) (rotation.Key, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.keys) >= s.maximum {
+		return nil, &rotation.CapacityError{Maximum: s.maximum}
+	}
+	s.issued++
+	key := &sliceKey{id: fmt.Sprintf("key-%d", s.issued), created: s.clock.Now(), active: true}
+	s.keys = append(s.keys, key)
+	return key, nil
+}
+
+func (s *sliceStore) DeleteKey(ctx context.Context, key rotation.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.keys.Contains(key) {
+		return fmt.Errorf("no such key %+v", key)
+	}
+	s.keys = s.keys.Without(rotation.KeyList{key})
+	return nil
+}
+
+func (s *sliceStore) DisableKey(ctx context.Context, key rotation.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key.(*sliceKey).active = false
+	return nil
+}
+
+func (s *sliceStore) ListKeys(ctx context.Context) (rotation.KeyList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return append(rotation.KeyList{}, s.keys...), nil
+}
+
+func (s *sliceStore) MaximumKeys() int {
+	return s.maximum
+}
+
+func TestConformingStore(t *testing.T) {
+	for _, maximum := range []int{1, 2, 5} {
+		t.Run(fmt.Sprintf("Maximum%d", maximum), func(t *testing.T) {
+			Run(t, func(t *testing.T, clock rotation.Clock) rotation.KeyStore {
+				return &sliceStore{clock: clock, maximum: maximum}
+			})
+		})
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := NewClock(Epoch)
+	clock.Advance(time.Hour)
+	if expected := Epoch.Add(time.Hour); !clock.Now().Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, clock.Now())
+	}
+	clock.Set(Epoch)
+	if !clock.Now().Equal(Epoch) {
+		t.Errorf("expected %s, got %s", Epoch, clock.Now())
+	}
+}
+
+func TestRealTimeStore(t *testing.T) {
+	//Ignoring the given clock, as stores backed by real systems do.
+	Run(t, func(t *testing.T, clock rotation.Clock) rotation.KeyStore {
+		return &sliceStore{clock: rotation.SystemClock, maximum: 2}
+	}, WithRealTime())
+}
@@ -2,6 +2,7 @@ package rotation
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -14,19 +15,115 @@ func InvalidTime() time.Time {
 //Key is a bridge to the underlying implementation of a particular KeyStore.
 type Key interface {
 	//Created provides the time the key was created.  If the key is otherwise not valid or inactive the key should provide
-	//rotation.InvalidTime() as the result of this invocation.
+	//rotation.InvalidTime() as the result of this invocation.  Keys implementing StatusReportingKey may instead report
+	//the actual creation time of inactive keys.
 	Created() time.Time
 }
 
-//KeyList is an anemic type reference for a set of keys...probably should add behavior or get rid of it.
-type KeyList []Key
+//StatusReportingKey is implemented by keys which may be inactive while still existing within their store.  Keys not
+//implementing this interface are considered active.
+type StatusReportingKey interface {
+	Key
+	//Active is false when the key has been disabled and is no longer usable against the target systems.
+	Active() bool
+}
+
+//IdentifiableKey is implemented by keys which carry a stable identifier within their KeyStore.  Identifiers are used
+//to persist plans and to reconcile them against the live state of a store.
+type IdentifiableKey interface {
+	Key
+	//KeyID is the identifier of the key within the KeyStore, such as an AWS access key ID.  Must never contain secret
+	//material.
+	KeyID() string
+}
+
+//ExpiringKey is implemented by keys which their store expires independently of rotation, such as certificates.
+type ExpiringKey interface {
+	Key
+	//Expires is when the store will stop honoring the key.  false is returned when the key does not expire.
+	Expires() (time.Time, bool)
+}
+
+//DescribedKey is implemented by keys able to describe themselves for humans beyond their identity, such as the owner
+//of the key or where it was last used.
+type DescribedKey interface {
+	Key
+	Description() string
+}
+
+//SecretBearingKey is implemented by keys carrying their secret material.  Most stores only reveal secrets as a key is
+//created.
+type SecretBearingKey interface {
+	Key
+	//SecretMaterial is the secret of the key.  false is returned when the secret is unknown.
+	SecretMaterial() (string, bool)
+}
+
+//IDOf extracts the identifier of the key, false if the key does not implement IdentifiableKey.
+func IDOf(k Key) (string, bool) {
+	if identity, ok := k.(IdentifiableKey); ok {
+		return identity.KeyID(), true
+	}
+	return "", false
+}
+
+//IsActive determines if the key is usable, considering keys which do not implement StatusReportingKey active.
+func IsActive(k Key) bool {
+	if status, ok := k.(StatusReportingKey); ok {
+		return status.Active()
+	}
+	return true
+}
+
+//LastUsedOf extracts when the key was last used, false if never used or the key does not implement
+//UsageReportingKey.
+func LastUsedOf(k Key) (time.Time, bool) {
+	if usage, ok := k.(UsageReportingKey); ok {
+		return usage.LastUsed()
+	}
+	return time.Time{}, false
+}
+
+//ExpiresOf extracts when the store will expire the key, false if the key does not expire or does not implement
+//ExpiringKey.
+func ExpiresOf(k Key) (time.Time, bool) {
+	if expiring, ok := k.(ExpiringKey); ok {
+		return expiring.Expires()
+	}
+	return time.Time{}, false
+}
+
+//DescriptionOf extracts the human description of the key, empty if the key does not implement DescribedKey.
+func DescriptionOf(k Key) string {
+	if described, ok := k.(DescribedKey); ok {
+		return described.Description()
+	}
+	return ""
+}
+
+//SecretOf extracts the secret material of the key, false if unknown or the key does not implement SecretBearingKey.
+func SecretOf(k Key) (string, bool) {
+	if secret, ok := k.(SecretBearingKey); ok {
+		return secret.SecretMaterial()
+	}
+	return "", false
+}
+
+//describeKey renders the identity of a key for messages.
+func describeKey(k Key) string {
+	if id, ok := IDOf(k); ok {
+		return id
+	}
+	return fmt.Sprintf("created %s", k.Created().Format(time.RFC3339))
+}
 
 //KeyStore abstracts operations to be performed against a key store for rotational capabilities.  These are typically
 //bound to a specific agent context such as a user or application.
 type KeyStore interface {
 
 	//CreateKey creates and persists a new key within the key store.  Key should be castable to the implementing type of
-	//the key store for extraction of the specific credentials.
+	//the key store for extraction of the specific credentials.  A key returned along with an error was created despite
+	//the failure of a later step, such as recording the key, and must be treated as existing.
 	CreateKey(ctx context.Context) (Key, error)
 
 	//DeleteKey causes the destruction of a given key from the store.  The key should no longer be operable against the
@@ -41,8 +138,20 @@ type KeyStore interface {
 	MaximumKeys() int
 }
 
+//DisablingKeyStore is an optional capability of a KeyStore able to deactivate keys without destroying them, allowing
+//the change to be reversed.
+type DisablingKeyStore interface {
+	KeyStore
+
+	//DisableKey deactivates the given key.  The key should no longer be operable against the target systems but should
+	//continue to be listed by the store.
+	DisableKey(ctx context.Context, key Key) error
+}
+
 //KeyStoreDecorator provides a minimal implementation of KeyStore delegating to the Wrapped keystore.  Intended to be
-//further extended to override specific behaviors of a KeyStore.
+//further extended to override specific behaviors of a KeyStore.  Decorators not overriding DisableKey and EnableKey
+//should be passed through PreserveCapabilities so the capabilities of the wrapped store remain available.  Every
+//decorator constructed by this package does so, offering DisableKey and EnableKey exactly when the wrapped store does.
 type KeyStoreDecorator struct {
 	Wrapped KeyStore
 }
@@ -59,3 +168,8 @@ func (k *KeyStoreDecorator) ListKeys(ctx context.Context) (KeyList, error) {
 func (k *KeyStoreDecorator) MaximumKeys() int {
 	return k.Wrapped.MaximumKeys()
 }
+
+//Unwrap exposes the wrapped store, allowing Find to discover capabilities through chains of decorators.
+func (k *KeyStoreDecorator) Unwrap() KeyStore {
+	return k.Wrapped
+}
@@ -1,6 +1,9 @@
 package rotation
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func buildKeystoreDecorator() KeyStore {
 	return &KeyStoreDecorator{Wrapped: nil}
@@ -11,3 +14,78 @@ func buildKeystoreDecorator() KeyStore {
 func TestKeyStoreDecorator_castableToKeyStore(t *testing.T) {
 	buildKeystoreDecorator()
 }
+
+//describedKey implements every optional key capability.
+type describedKey struct {
+	*mockKey
+	expires time.Time
+	secret  string
+}
+
+func (d *describedKey) Expires() (time.Time, bool) {
+	return d.expires, !d.expires.IsZero()
+}
+
+func (d *describedKey) Description() string {
+	return "test key"
+}
+
+func (d *describedKey) SecretMaterial() (string, bool) {
+	return d.secret, d.secret != ""
+}
+
+//bareKey implements no optional key capabilities.
+type bareKey struct {
+	created time.Time
+}
+
+func (b *bareKey) Created() time.Time {
+	return b.created
+}
+
+func TestKeyCapabilities(t *testing.T) {
+	used := mockNow.Add(-time.Minute)
+	full := &describedKey{
+		mockKey: &mockKey{id: "key-1", created: mockNow, lastUsed: used, inactive: true},
+		expires: mockNow.Add(time.Hour),
+		secret:  "hunter2",
+	}
+	if id, ok := IDOf(full); !ok || id != "key-1" {
+		t.Errorf("Expected ID key-1, got %q", id)
+	}
+	if IsActive(full) {
+		t.Error("Expected key to be inactive")
+	}
+	if at, ok := LastUsedOf(full); !ok || !at.Equal(used) {
+		t.Errorf("Expected last use at %s, got %s", used, at)
+	}
+	if at, ok := ExpiresOf(full); !ok || !at.Equal(full.expires) {
+		t.Errorf("Expected expiry at %s, got %s", full.expires, at)
+	}
+	if description := DescriptionOf(full); description != "test key" {
+		t.Errorf("Expected description, got %q", description)
+	}
+	if secret, ok := SecretOf(full); !ok || secret != "hunter2" {
+		t.Errorf("Expected secret, got %q", secret)
+	}
+
+	bare := &bareKey{created: mockNow}
+	if _, ok := IDOf(bare); ok {
+		t.Error("Expected no ID")
+	}
+	if !IsActive(bare) {
+		t.Error("Expected keys without a status to be active")
+	}
+	if _, ok := LastUsedOf(bare); ok {
+		t.Error("Expected no last use")
+	}
+	if _, ok := ExpiresOf(bare); ok {
+		t.Error("Expected no expiry")
+	}
+	if DescriptionOf(bare) != "" {
+		t.Error("Expected no description")
+	}
+	if _, ok := SecretOf(bare); ok {
+		t.Error("Expected no secret")
+	}
+}
@@ -0,0 +1,186 @@
+package rotation
+
+import (
+	"context"
+	"sync"
+)
+
+//Tracer begins spans for the operations of this package.  Implementations adapt a tracing library, such as the
+//OpenTelemetry adapter in the oteltracing package, and find the parent span within the context.
+type Tracer interface {
+	//Start begins a span named for the operation, returning a context carrying the span.
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+}
+
+//Span is a single traced operation.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	//End completes the span, marking it failed when err is not nil.
+	End(err error)
+}
+
+//Attribute annotates a span.  Value is a string, int, or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+//attributeKey names an attribute recorded by this package.
+type attributeKey string
+
+func (k attributeKey) String(value string) Attribute { return Attribute{Key: string(k), Value: value} }
+func (k attributeKey) Int(value int) Attribute       { return Attribute{Key: string(k), Value: value} }
+func (k attributeKey) Bool(value bool) Attribute     { return Attribute{Key: string(k), Value: value} }
+
+//noopTracer discards all spans.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End(error)                  {}
+
+var (
+	tracerLock       sync.RWMutex
+	registeredTracer Tracer = noopTracer{}
+)
+
+//SetTracer registers the tracer producing spans for this package.  Spans are discarded until a tracer is registered;
+//a nil tracer restores discarding.
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracerLock.Lock()
+	defer tracerLock.Unlock()
+	registeredTracer = t
+}
+
+//tracer returns the currently registered tracer.
+func tracer() Tracer {
+	tracerLock.RLock()
+	defer tracerLock.RUnlock()
+	return registeredTracer
+}
+
+var (
+	attributeTarget   = attributeKey("key_rotation.target")
+	attributePolicy   = attributeKey("key_rotation.policy")
+	attributeKeyID    = attributeKey("key_rotation.key_id")
+	attributeOutcome  = attributeKey("key_rotation.outcome")
+	attributeResult   = attributeKey("key_rotation.result")
+	attributeCreate   = attributeKey("key_rotation.plan.create")
+	attributeDisable  = attributeKey("key_rotation.plan.disable")
+	attributeDestroy  = attributeKey("key_rotation.plan.destroy")
+	attributeKeyCount = attributeKey("key_rotation.keys")
+)
+
+//targetContextKey carries the name of the target being rotated within a context.
+type targetContextKey struct{}
+
+//withTarget records the target being rotated within the context, attributing spans of planners to it.
+func withTarget(ctx context.Context, target string) context.Context {
+	return context.WithValue(ctx, targetContextKey{}, target)
+}
+
+//targetAttributes names the target of spans concerning the store, taken from the context or else from a
+//TracingKeyStore within the store.  Empty when the target is unknown.
+func targetAttributes(ctx context.Context, store KeyStore) []Attribute {
+	if target, ok := ctx.Value(targetContextKey{}).(string); ok {
+		return []Attribute{attributeTarget.String(target)}
+	}
+	if tracing, ok := Find[*TracingKeyStore](store); ok {
+		return []Attribute{attributeTarget.String(tracing.target)}
+	}
+	return nil
+}
+
+//endSpan records the outcome of the operation before ending the span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.SetAttributes(attributeOutcome.String(string(AuditFailure)))
+	} else {
+		span.SetAttributes(attributeOutcome.String(string(AuditSuccess)))
+	}
+	span.End(err)
+}
+
+//endPlanSpan summarizes the plan within the span before ending it.
+func endPlanSpan(span Span, plan *KeyRotationPlan, err error) {
+	if plan != nil {
+		span.SetAttributes(
+			attributePolicy.String(plan.Policy.Name),
+			attributeKeyCount.Int(len(plan.Classification)),
+			attributeCreate.Bool(plan.CreateKey),
+			attributeDisable.Int(len(plan.DisableKeys)),
+			attributeDestroy.Int(len(plan.DestroyKeys)),
+		)
+	}
+	endSpan(span, err)
+}
+
+//TracingKeyStore produces a span for each call to the wrapped store.  Key IDs are recorded, secrets never are.
+type TracingKeyStore struct {
+	KeyStoreDecorator
+	target string
+}
+
+//NewTracingKeyStore wraps the store, tracing each call.  Spans are attributed to the given target.
+func NewTracingKeyStore(store KeyStore, target string) KeyStore {
+	tracing := &TracingKeyStore{
+		KeyStoreDecorator: KeyStoreDecorator{Wrapped: store},
+		target:            target,
+	}
+	return PreserveCapabilities(tracing)
+}
+
+//start begins a span for a call against the key, which may be nil.
+func (t *TracingKeyStore) start(ctx context.Context, name string, key Key) (context.Context, Span) {
+	attributes := []Attribute{attributeTarget.String(t.target)}
+	if key != nil {
+		attributes = append(attributes, attributeKeyID.String(describeKey(key)))
+	}
+	return tracer().Start(ctx, name, attributes...)
+}
+
+func (t *TracingKeyStore) CreateKey(ctx context.Context) (key Key, err error) {
+	ctx, span := t.start(ctx, "KeyStore.CreateKey", nil)
+	defer func() {
+		if key != nil {
+			span.SetAttributes(attributeKeyID.String(describeKey(key)))
+		}
+		endSpan(span, err)
+	}()
+	return t.Wrapped.CreateKey(ctx)
+}
+
+func (t *TracingKeyStore) DeleteKey(ctx context.Context, key Key) (err error) {
+	ctx, span := t.start(ctx, "KeyStore.DeleteKey", key)
+	defer func() { endSpan(span, err) }()
+	return t.Wrapped.DeleteKey(ctx, key)
+}
+
+func (t *TracingKeyStore) ListKeys(ctx context.Context) (keys KeyList, err error) {
+	ctx, span := t.start(ctx, "KeyStore.ListKeys", nil)
+	defer func() {
+		span.SetAttributes(attributeKeyCount.Int(len(keys)))
+		endSpan(span, err)
+	}()
+	return t.Wrapped.ListKeys(ctx)
+}
+
+func (t *TracingKeyStore) DisableKey(ctx context.Context, key Key) (err error) {
+	ctx, span := t.start(ctx, "KeyStore.DisableKey", key)
+	defer func() { endSpan(span, err) }()
+	return disableWith(ctx, t.Wrapped, key)
+}
+
+func (t *TracingKeyStore) EnableKey(ctx context.Context, key Key) (err error) {
+	ctx, span := t.start(ctx, "KeyStore.EnableKey", key)
+	defer func() { endSpan(span, err) }()
+	return enableWith(ctx, t.Wrapped, key)
+}
@@ -0,0 +1,149 @@
+package rotation
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+//recordedSpan is a span captured by recordingTracer.
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...Attribute) {
+	for _, a := range attributes {
+		s.attributes[a.Key] = a.Value
+	}
+}
+
+//recordingTracer captures ended spans in the order they end.
+type recordingTracer struct {
+	lock  sync.Mutex
+	ended []*recordedSpan
+}
+
+type spanContextKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(spanContextKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	span.SetAttributes(attributes...)
+	return context.WithValue(ctx, spanContextKey{}, span), &recordingSpan{recordedSpan: span, tracer: r}
+}
+
+type recordingSpan struct {
+	*recordedSpan
+	tracer *recordingTracer
+}
+
+func (s *recordingSpan) End(err error) {
+	s.err = err
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	s.tracer.ended = append(s.tracer.ended, s.recordedSpan)
+}
+
+func recordSpans(t *testing.T) *recordingTracer {
+	t.Helper()
+	recorder := &recordingTracer{}
+	SetTracer(recorder)
+	t.Cleanup(func() { SetTracer(nil) })
+	return recorder
+}
+
+func TestTracesPlanApplyAndStoreCalls(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	mock.mockExpired(5)
+	store := NewTracingKeyStore(mock, "alice")
+	plan := planFor(t, store)
+	plan.Target = "alice"
+	_, err := plan.Apply(ctx, store)
+	assertNoError(t, err)
+
+	spans := recorder.ended
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.name
+	}
+	expected := []string{"KeyStore.ListKeys", "GracefulExpiration.Plan", "KeyStore.CreateKey", "KeyStore.DeleteKey", "KeyRotationPlan.Apply"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected spans %q, got %q", expected, names)
+	}
+	for i, name := range expected {
+		if names[i] != name {
+			t.Errorf("Expected span %d to be %q, got %q", i, name, names[i])
+		}
+	}
+
+	apply := spans[4]
+	for _, s := range spans[2:4] {
+		if s.parent != apply {
+			t.Errorf("Expected %s to be a child of the apply span", s.name)
+		}
+	}
+	if id := spans[3].attributes[string(attributeKeyID)]; id != "mock-1" {
+		t.Errorf("Expected deleted key ID to be recorded, got %v", id)
+	}
+	if id := spans[2].attributes[string(attributeKeyID)]; id != "mock-2" {
+		t.Errorf("Expected created key ID to be recorded, got %v", id)
+	}
+	if target := apply.attributes[string(attributeTarget)]; target != "alice" {
+		t.Errorf("Expected target to be recorded, got %v", target)
+	}
+	if target := spans[1].attributes[string(attributeTarget)]; target != "alice" {
+		t.Errorf("Expected target to be recorded on the planning span, got %v", target)
+	}
+	if destroy := spans[1].attributes[string(attributeDestroy)]; destroy != 1 {
+		t.Errorf("Expected plan to record a single destroyed key, got %v", destroy)
+	}
+}
+
+func TestTracesRecordFailures(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, done := testContext(t)
+	defer done()
+
+	mock := newMock()
+	mock.createErr = errTransient
+	store := NewTracingKeyStore(mock, "alice")
+	if _, err := store.CreateKey(ctx); err == nil {
+		t.Fatal("Expected creation to fail")
+	}
+
+	spans := recorder.ended
+	if len(spans) != 1 || spans[0].err == nil {
+		t.Fatalf("Expected a single failed span, got %+v", spans)
+	}
+	if outcome := spans[0].attributes[string(attributeOutcome)]; outcome != string(AuditFailure) {
+		t.Errorf("Expected failure outcome, got %v", outcome)
+	}
+}
+
+func TestTracesAttributePlanningToExecutorTarget(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, done := testContext(t)
+	defer done()
+
+	executor := testExecutor()
+	executor.PlanOnly = true
+	executor.Run(ctx, []Target{{Name: "bob", Store: newMock()}})
+
+	for _, s := range recorder.ended {
+		if s.name != "GracefulExpiration.Plan" {
+			continue
+		}
+		if target := s.attributes[string(attributeTarget)]; target != "bob" {
+			t.Errorf("Expected planning span to name the target, got %v", target)
+		}
+		return
+	}
+	t.Error("Expected a planning span")
+}
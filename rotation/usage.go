@@ -0,0 +1,44 @@
+package rotation
+
+import (
+	"fmt"
+	"time"
+)
+
+//UsageReportingKey is implemented by keys whose store records when the key was last used.
+type UsageReportingKey interface {
+	Key
+	//LastUsed is the most recent time the key was used.  false is returned when the key has never been used or the
+	//store does not know.
+	LastUsed() (time.Time, bool)
+}
+
+//UsageGuard determines how grace keys used within a recent window are protected from destruction.
+type UsageGuard string
+
+const (
+	//UsageGuardRefuse never destroys a grace key used within the usage window, failing the plan when no other grace key
+	//may be evicted.
+	UsageGuardRefuse UsageGuard = "refuse"
+	//UsageGuardWarn destroys recently used grace keys but records a warning within the plan.
+	UsageGuardWarn UsageGuard = "warn"
+)
+
+//ParseUsageGuard interprets the name of a UsageGuard.
+func ParseUsageGuard(name string) (UsageGuard, error) {
+	switch guard := UsageGuard(name); guard {
+	case UsageGuardRefuse, UsageGuardWarn:
+		return guard, nil
+	default:
+		return "", fmt.Errorf("no such usage guard %q", name)
+	}
+}
+
+//usedWithin determines if the key was used within the window preceding now, returning the time of last use.
+func usedWithin(k Key, now time.Time, window time.Duration) (time.Time, bool) {
+	used, ok := LastUsedOf(k)
+	if !ok || window <= 0 {
+		return used, false
+	}
+	return used, !used.Before(now.Add(-1 * window))
+}
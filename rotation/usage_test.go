@@ -0,0 +1,93 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func harnessUsagePlan(t *testing.T, store KeyStore, guard UsageGuard) (*KeyRotationPlan, error) {
+	ctx, done := testContext(t)
+	defer done()
+
+	rotation, err := NewGracefulExpiration(1*time.Minute, 30*time.Second, WithClock(FixedClock(mockNow)), WithUsageGuard(10*time.Second, guard))
+	assertNoError(t, err)
+	return rotation.Plan(ctx, store)
+}
+
+func TestUsageGuardRefuseSkipsRecentlyUsedGraceKey(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	store.appendKeyExpiring(50).lastUsed = mockNow.Add(-5 * time.Second)
+	unused := store.appendKeyExpiring(40)
+
+	plan, err := harnessUsagePlan(t, store, UsageGuardRefuse)
+	assertNoError(t, err)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != unused {
+		t.Errorf("Expected unused grace key to be destroyed, got %+v", plan.DestroyKeys)
+	}
+	if len(plan.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %q", plan.Warnings)
+	}
+}
+
+func TestUsageGuardRefuseFailsWhenAllGraceKeysUsed(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	store.appendKeyExpiring(50).lastUsed = mockNow.Add(-5 * time.Second)
+	store.appendKeyExpiring(40).lastUsed = mockNow.Add(-1 * time.Second)
+
+	if _, err := harnessUsagePlan(t, store, UsageGuardRefuse); err == nil {
+		t.Error("Expected plan to refuse destroying recently used keys")
+	}
+}
+
+func TestUsageGuardWarnDestroysWithWarning(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	used := store.appendKeyExpiring(50)
+	used.lastUsed = mockNow.Add(-5 * time.Second)
+	store.appendKeyExpiring(40)
+
+	plan, err := harnessUsagePlan(t, store, UsageGuardWarn)
+	assertNoError(t, err)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != used {
+		t.Errorf("Expected oldest grace key to be destroyed, got %+v", plan.DestroyKeys)
+	}
+	if len(plan.Warnings) != 1 {
+		t.Errorf("Expected a warning, got %q", plan.Warnings)
+	}
+}
+
+func TestUsageGuardIgnoresUseOutsideWindow(t *testing.T) {
+	store := newMock()
+	store.maximumCount = 2
+	old := store.appendKeyExpiring(50)
+	old.lastUsed = mockNow.Add(-20 * time.Second)
+	store.appendKeyExpiring(40).lastUsed = mockNow.Add(-1 * time.Second)
+
+	plan, err := harnessUsagePlan(t, store, UsageGuardRefuse)
+	assertNoError(t, err)
+	if len(plan.DestroyKeys) != 1 || plan.DestroyKeys[0] != old {
+		t.Errorf("Expected key used outside the window to be destroyed, got %+v", plan.DestroyKeys)
+	}
+}
+
+func TestUsageGuardDestroysExpiredKeys(t *testing.T) {
+	store := newMock()
+	store.mockExpired(5).lastUsed = mockNow.Add(-1 * time.Second)
+
+	plan, err := harnessUsagePlan(t, store, UsageGuardRefuse)
+	assertNoError(t, err)
+	plan.assertDestroying(t, 1)
+}
+
+func TestParseUsageGuard(t *testing.T) {
+	for _, name := range []string{"refuse", "warn"} {
+		if _, err := ParseUsageGuard(name); err != nil {
+			t.Errorf("Expected %q to parse, got %s", name, err.Error())
+		}
+	}
+	if _, err := ParseUsageGuard("ignore"); err == nil {
+		t.Error("Expected unknown guard to be rejected")
+	}
+}